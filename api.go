@@ -0,0 +1,367 @@
+package spotify
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 接口的错误响应均为 {"error": {"code": ..., "message": ...}}
+const (
+	APIErrorInvalidParameter = "invalid_parameter"
+	APIErrorNotFound         = "not_found"
+	APIErrorMethodNotAllowed = "method_not_allowed"
+	APIErrorInternal         = "internal_error"
+)
+
+const (
+	apiDefaultLimit    = 50
+	apiMaxHistoryLimit = 1000
+	apiMaxTopsLimit    = 500
+	apiMaxRangeDays    = 366
+)
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func invalidParameter(name, message string) *apiError {
+	return &apiError{http.StatusBadRequest, APIErrorInvalidParameter, name + ": " + message}
+}
+
+// HistoryPage 是 /history 的响应, Items 中的单集会被跳过, 数量可能少于 Limit
+type HistoryPage struct {
+	Total  int64         `json:"total"`
+	Offset int64         `json:"offset"`
+	Limit  int64         `json:"limit"`
+	Order  string        `json:"order"`
+	Items  []PlayedTrack `json:"items"`
+}
+
+// DailyRange 是 /ranges 的响应中的一项, 没有播放记录的日期会被跳过
+type DailyRange struct {
+	Date  string `json:"date"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Count int    `json:"count"`
+}
+
+// NowPlaying 是 /now 的响应
+type NowPlaying struct {
+	IsPlaying bool              `json:"is_playing"`
+	Item      *CurrentlyPlaying `json:"item"`
+}
+
+// NewHandler 返回提供 JSON 接口的 http.Handler, 只接受 GET 与 HEAD 请求, 可通过 http.StripPrefix 挂载在其它路径下
+//
+//	GET /history?offset=0&limit=50&order=desc   播放记录, order 为 desc(从最新开始) 或 asc
+//	GET /tops?entity=tracks&from=&to=&limit=50  一段时间内(包括 from 和 to)的热门曲目 艺术家或专辑, 日期格式为 time.DateOnly
+//	GET /hourly                                 每个小时的收听量
+//	GET /ranges?from=&to=                       每日播放记录的范围与收听量
+//	GET /now                                    正在播放, 会请求 Spotify
+//	GET /events                                 以 Server-Sent Events 推送 LiveEvent, 需要同时运行 RunLivePoller
+//	GET /metrics                                Prometheus 指标, 见 MetricsHandler
+//	GET /dashboard/                             网页仪表盘, 见 DashboardHandler
+//	GET|POST /graphql                           GraphQL 接口, 见 GraphQLHandler
+func (c *Client) NewHandler(dbc dbClient) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/history", c.apiHandler(dbc, c.serveHistory))
+	mux.Handle("/tops", c.apiHandler(dbc, c.serveTops))
+	mux.Handle("/hourly", c.apiHandler(dbc, c.serveHourly))
+	mux.Handle("/ranges", c.apiHandler(dbc, c.serveRanges))
+	mux.Handle("/now", c.apiHandler(dbc, c.serveNowPlaying))
+	mux.HandleFunc("/events", c.serveEvents)
+	mux.Handle("/metrics", c.MetricsHandler(dbc))
+	mux.Handle("/dashboard/", http.StripPrefix("/dashboard", c.DashboardHandler(dbc)))
+	mux.Handle("/graphql", c.GraphQLHandler(dbc))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, &apiError{http.StatusNotFound, APIErrorNotFound, "接口不存在"})
+	})
+
+	return mux
+}
+
+type apiFunc func(dbc dbClient, r *http.Request) (any, error)
+
+// apiHandler 检查请求方法, 将 f 的返回值编码为 JSON 并处理 ETag 与错误
+func (c *Client) apiHandler(dbc dbClient, f apiFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeAPIError(w, &apiError{http.StatusMethodNotAllowed, APIErrorMethodNotAllowed, "只接受 GET 与 HEAD 请求"})
+			return
+		}
+
+		v, err := f(dbc, r)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		sum := sha1.Sum(b)
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_, err = w.Write(b)
+		if err != nil {
+			slog.Debug("写入响应失败", "error", err)
+		}
+	})
+}
+
+// etagMatches 判断 If-None-Match 中是否包含 etag, 弱校验的 W/ 前缀会被忽略
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, s := range strings.Split(ifNoneMatch, ",") {
+		s = strings.TrimPrefix(strings.TrimSpace(s), "W/")
+		if s == etag || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	var ae *apiError
+	if !errors.As(err, &ae) {
+		slog.Warn("接口请求处理失败", "error", err)
+		ae = &apiError{http.StatusInternalServerError, APIErrorInternal, "服务器内部错误"}
+	}
+
+	var buf bytes.Buffer
+
+	_ = json.NewEncoder(&buf).Encode(map[string]any{
+		"error": map[string]string{"code": ae.code, "message": ae.message},
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(ae.status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// parseIntParam 解析整数参数, 参数为空时返回 def
+func parseIntParam(r *http.Request, name string, def, min, max int64) (int64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, invalidParameter(name, "应为整数")
+	}
+
+	if n < min || n > max {
+		return 0, invalidParameter(name, "应在 "+strconv.FormatInt(min, 10)+" 与 "+strconv.FormatInt(max, 10)+" 之间")
+	}
+
+	return n, nil
+}
+
+// parseDateRangeParams 解析查询参数 from 与 to, 见 parseDateRange
+func parseDateRangeParams(r *http.Request) (time.Time, time.Time, error) {
+	return parseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+}
+
+// parseDate 解析 time.DateOnly 格式的日期, name 为参数名, 用于错误信息
+func parseDate(name, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, invalidParameter(name, "不能为空")
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, invalidParameter(name, "日期格式应为 "+time.DateOnly)
+	}
+
+	return t, nil
+}
+
+// parseDateRange 解析 from 与 to, 均为必填, 范围不能超过 apiMaxRangeDays 天
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	t1, err := parseDate("from", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	t2, err := parseDate("to", to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if t2.Before(t1) {
+		return time.Time{}, time.Time{}, invalidParameter("to", "不能早于 from")
+	}
+
+	if t2.After(t1.AddDate(0, 0, apiMaxRangeDays)) {
+		return time.Time{}, time.Time{}, invalidParameter("to", "与 from 相差不能超过 "+strconv.Itoa(apiMaxRangeDays)+" 天")
+	}
+
+	return t1, t2, nil
+}
+
+func (c *Client) serveHistory(dbc dbClient, r *http.Request) (any, error) {
+	offset, err := parseIntParam(r, "offset", 0, 0, 1<<53)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseIntParam(r, "limit", apiDefaultLimit, 1, apiMaxHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = "desc"
+	}
+
+	if order != "desc" && order != "asc" {
+		return nil, invalidParameter("order", "应为 desc 或 asc")
+	}
+
+	total, err := c.GetTotalPlaybackHistoryCount(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	page := HistoryPage{Total: total, Offset: offset, Limit: limit, Order: order, Items: []PlayedTrack{}}
+
+	if offset >= total {
+		return page, nil
+	}
+
+	start, stop := historyWindow(total, offset, limit, order == "desc")
+
+	items, err := c.GetPlaybackHistory(dbc, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	if order == "desc" {
+		slices.Reverse(items)
+	}
+
+	if items != nil {
+		page.Items = items
+	}
+
+	return page, nil
+}
+
+// historyWindow 返回从第 offset 条开始的 limit 条播放记录在数据库列表中的下标范围, desc 时从最新开始计数, offset 应小于 total
+func historyWindow(total, offset, limit int64, desc bool) (int64, int64) {
+	start, stop := offset, min(offset+limit, total)-1
+	if desc {
+		start, stop = total-1-stop, total-1-start
+	}
+	return start, stop
+}
+
+func (c *Client) serveTops(dbc dbClient, r *http.Request) (any, error) {
+	entity := r.URL.Query().Get("entity")
+	if entity == "" {
+		entity = ChartEntityTracks
+	}
+
+	if !slices.Contains(chartEntities, entity) {
+		return nil, invalidParameter("entity", "应为 tracks, artists 或 albums")
+	}
+
+	t1, t2, err := parseDateRangeParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseIntParam(r, "limit", apiDefaultLimit, 1, apiMaxTopsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := c.GetTops(dbc, entity, t1, t2, int(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if items == nil {
+		items = []TopItem{}
+	}
+
+	return items, nil
+}
+
+func (c *Client) serveHourly(dbc dbClient, r *http.Request) (any, error) {
+	return c.GetHourlyPlayBackCounts(dbc)
+}
+
+func (c *Client) serveRanges(dbc dbClient, r *http.Request) (any, error) {
+	t1, t2, err := parseDateRangeParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.getDailyRanges(dbc, t1, t2)
+}
+
+// getDailyRanges 返回一段时间内(包括t1和t2)每日播放记录的范围与收听量, 没有播放记录的日期会被跳过
+func (c *Client) getDailyRanges(dbc dbClient, t1, t2 time.Time) ([]DailyRange, error) {
+	ranges := []DailyRange{}
+
+	for t := t1; !t.After(t2); t = t.AddDate(0, 0, 1) {
+		pr, err := c.GetPlaybackRangeOnADay(dbc, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if pr == nil {
+			continue
+		}
+
+		ranges = append(ranges, DailyRange{t.Format(time.DateOnly), pr.Start, pr.End, pr.End - pr.Start + 1})
+	}
+
+	return ranges, nil
+}
+
+func (c *Client) serveNowPlaying(dbc dbClient, r *http.Request) (any, error) {
+	cp, err := c.GetCurrentlyPlayingTrack(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	return NowPlaying{cp != nil, cp}, nil
+}
@@ -188,6 +188,66 @@ func getToken(dbc dbClient, key []byte) (*oauth2.Token, error) {
 	return finalToken, nil
 }
 
+// ErrNoToken 表示数据库中没有可用的 Spotify 令牌, 需要通过 GetClient 登录
+var ErrNoToken = errors.New("没有可用的 Spotify 令牌, 请先登录")
+
+// LoadClient 使用数据库中的令牌创建客户端, key 为 Base64 编码的 SPOTIFY_KEY
+// 与 GetClient 不同, 不会启动网页授权流程或退出程序, 没有可用的令牌时返回的错误包含 ErrNoToken
+func LoadClient(dbc dbClient, key []byte) (*Client, error) {
+	if len(key) == 0 {
+		return nil, errors.New("未设置 SPOTIFY_KEY")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(key))
+	if err != nil {
+		return nil, fmt.Errorf("Base64 解码 SPOTIFY_KEY 失败: %w", err)
+	}
+
+	return loadClient(context.Background(), dbc, key)
+}
+
+// loadClient 使用数据库中的令牌创建客户端, 访问令牌过期时会刷新并保存
+func loadClient(ctx context.Context, dbc dbClient, key []byte) (*Client, error) {
+	persistedToken, err := getToken(dbc, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoToken, err)
+	}
+
+	if persistedToken.Valid() {
+		httpClient := auth.Client(ctx, persistedToken)
+		spotifyClient := spotify.New(instrumentHTTPClient(httpClient))
+		return &Client{C: spotifyClient, Ctx: ctx}, nil
+	}
+
+	if persistedToken.RefreshToken == "" {
+		return nil, fmt.Errorf("%w: 访问令牌已过期且没有刷新令牌", ErrNoToken)
+	}
+
+	slog.Debug("访问令牌已过期或无效，但有刷新令牌，尝试刷新...")
+
+	newToken, err := auth.RefreshToken(ctx, persistedToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 刷新令牌失败: %w", ErrNoToken, err)
+	}
+
+	httpClient := auth.Client(ctx, newToken)
+	spotifyClient := spotify.New(instrumentHTTPClient(httpClient))
+
+	_, err = spotifyClient.CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 使用已刷新的令牌测试 API 调用失败: %w", ErrNoToken, err)
+	}
+
+	slog.Debug("使用已加载/已刷新的令牌创建客户端成功")
+
+	if err = saveToken(dbc, newToken, key); err != nil {
+		slog.Warn("无法保存令牌", "error", err)
+		// 即使保存失败，本次会话仍然可以使用这个令牌
+	}
+
+	return &Client{C: spotifyClient, Ctx: ctx}, nil
+}
+
 func GetClient(dbc dbClient, key []byte) *Client {
 	if key == nil || len(key) == 0 {
 		str, err := dbc.GetString("spotify-token")
@@ -213,37 +273,12 @@ func GetClient(dbc dbClient, key []byte) *Client {
 
 	ctx := context.Background()
 
-	persistedToken, err := getToken(dbc, key)
-	if err == nil && persistedToken.Valid() {
-		httpClient := auth.Client(ctx, persistedToken)
-		spotifyClient := spotify.New(httpClient)
-		return &Client{C: spotifyClient, Ctx: ctx}
-	}
-
-	if err == nil && persistedToken.RefreshToken != "" {
-		slog.Debug("访问令牌已过期或无效，但有刷新令牌，尝试刷新...")
-
-		newToken, err := auth.RefreshToken(ctx, persistedToken)
-		httpClient := auth.Client(ctx, newToken)
-		spotifyClient := spotify.New(httpClient)
-
-		_, testErr := spotifyClient.CurrentUser(ctx)
-		if testErr == nil {
-			slog.Debug("使用已加载/已刷新的令牌创建客户端成功")
-
-			if err = saveToken(dbc, newToken, key); err != nil {
-				slog.Warn("无法保存令牌", "error", err)
-				// 即使保存失败，本次会话仍然可以使用这个令牌
-			}
-
-			return &Client{C: spotifyClient, Ctx: ctx}
-		}
-		slog.Warn("使用加载的令牌创建客户端后, 测试API调用失败, 将进行网页授权", "error", testErr)
+	c, err := loadClient(ctx, dbc, key)
+	if err == nil {
+		return c
 	}
 
-	if err != nil {
-		slog.Info("加载令牌失败或令牌无效, 将启动网页授权流程", "error", err)
-	}
+	slog.Info("加载令牌失败或令牌无效, 将启动网页授权流程", "error", err)
 
 	server := &http.Server{Addr: ":8080"}
 	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
@@ -286,7 +321,7 @@ func completeAuthAndSaveToken(dbc dbClient, w http.ResponseWriter, r *http.Reque
 		// 即使保存失败，本次会话仍然可以使用这个令牌
 	}
 
-	client := spotify.New(auth.Client(ctx, tok))
+	client := spotify.New(instrumentHTTPClient(auth.Client(ctx, tok)))
 
 	_, err = w.Write([]byte("登录成功"))
 	if err != nil {
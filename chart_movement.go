@@ -0,0 +1,242 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ChartMovement 的 Status 字段
+const (
+	MovementUp         = "up"
+	MovementDown       = "down"
+	MovementSame       = "same"
+	MovementNewEntry   = "new-entry"   // 第一次上榜
+	MovementReEntry    = "re-entry"    // 上一期不在榜上, 更早的某一期在榜上
+	MovementDroppedOut = "dropped-out" // 上一期在榜上, 这一期不在榜上
+)
+
+const (
+	TermMonthly    = "monthly"
+	TermHalfYearly = "half-yearly"
+	TermYearly     = "yearly"
+)
+
+var topTerms = []string{TermMonthly, TermHalfYearly, TermYearly}
+
+var errInvalidTopSnapshot = errors.New("无效的排行榜时间范围或类型")
+
+// ChartMovement 是两期排行榜之间一项的排名变化, 排名从 1 开始, 0 表示不在榜上
+type ChartMovement struct {
+	ID           string `json:"id"`
+	PreviousRank int    `json:"previous_rank"`
+	Rank         int    `json:"rank"`
+	Delta        int    `json:"delta"` // PreviousRank - Rank, 正数为上升, 新上榜与跌出榜单为 0
+	Status       string `json:"status"`
+}
+
+// diffRankings 比较两期排行榜, 返回这一期的所有项(按排名)与跌出榜单的项(按上一期的排名), earlier 为更早的各期出现过的 ID
+func diffRankings(previous, current []string, earlier map[string]bool) []ChartMovement {
+	previousRanks := map[string]int{}
+	for i, id := range previous {
+		previousRanks[id] = i + 1
+	}
+
+	currentRanks := map[string]int{}
+	for i, id := range current {
+		currentRanks[id] = i + 1
+	}
+
+	var movements []ChartMovement
+
+	for i, id := range current {
+		m := ChartMovement{ID: id, PreviousRank: previousRanks[id], Rank: i + 1}
+
+		switch {
+		case m.PreviousRank == 0 && earlier[id]:
+			m.Status = MovementReEntry
+		case m.PreviousRank == 0:
+			m.Status = MovementNewEntry
+		case m.PreviousRank > m.Rank:
+			m.Status = MovementUp
+		case m.PreviousRank < m.Rank:
+			m.Status = MovementDown
+		default:
+			m.Status = MovementSame
+		}
+
+		if m.PreviousRank != 0 {
+			m.Delta = m.PreviousRank - m.Rank
+		}
+
+		movements = append(movements, m)
+	}
+
+	for i, id := range previous {
+		if currentRanks[id] == 0 {
+			movements = append(movements, ChartMovement{ID: id, PreviousRank: i + 1, Status: MovementDroppedOut})
+		}
+	}
+
+	return movements
+}
+
+func topsToIDs(tops []Tops) []string {
+	var ids []string
+	for _, top := range tops {
+		ids = append(ids, top.ID)
+	}
+	return ids
+}
+
+func topSnapshotKey(term, entity string) string {
+	return term + "-top-" + entity
+}
+
+func isValidTopSnapshot(term, entity string) bool {
+	return slices.Contains(topTerms, term) && slices.Contains(chartEntities, entity)
+}
+
+// getSnapshotDates 返回数据库字典 key 中的所有日期, 按时间排序
+func getSnapshotDates(dbc dbClient, key string) ([]string, error) {
+	all, err := dbc.GetMapAll(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []string
+
+	for date := range all {
+		dates = append(dates, date)
+	}
+
+	slices.Sort(dates)
+
+	return dates, nil
+}
+
+// getTopSnapshotIDs 返回 Spotify 排行榜快照中的 ID, 若不存在会返回 nil
+func getTopSnapshotIDs(dbc dbClient, key, date string) ([]string, error) {
+	s, err := dbc.GetMapStr(key, date)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	var ids []string
+
+	err = json.Unmarshal([]byte(s), &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetTopSnapshotMovements 比较 Spotify 排行榜(如 monthly-top-artists)两个日期的快照, term 应使用 TermMonthly 等, entity 应使用 ChartEntityTracks 等
+// date1 为零值时与 date2 之前的上一次快照比较, 若快照不存在会返回 nil
+func (c *Client) GetTopSnapshotMovements(dbc dbClient, term, entity string, date1, date2 time.Time) ([]ChartMovement, error) {
+	if !isValidTopSnapshot(term, entity) {
+		return nil, errInvalidTopSnapshot
+	}
+
+	key := topSnapshotKey(term, entity)
+
+	dates, err := getSnapshotDates(dbc, key)
+	if err != nil {
+		return nil, err
+	}
+
+	d2 := date2.Format(time.DateOnly)
+	i2 := slices.Index(dates, d2)
+	if i2 == -1 {
+		return nil, nil
+	}
+
+	i1 := i2 - 1
+	if !date1.IsZero() {
+		i1 = slices.Index(dates, date1.Format(time.DateOnly))
+	}
+
+	if i1 < 0 || i1 >= i2 {
+		return nil, nil
+	}
+
+	previous, err := getTopSnapshotIDs(dbc, key, dates[i1])
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := getTopSnapshotIDs(dbc, key, d2)
+	if err != nil {
+		return nil, err
+	}
+
+	earlier := map[string]bool{}
+
+	for _, date := range dates[:i1] {
+		ids, err := getTopSnapshotIDs(dbc, key, date)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			earlier[id] = true
+		}
+	}
+
+	return diffRankings(previous, current, earlier), nil
+}
+
+// GetChartMovements 比较 date 所在周期与上一个周期的排行榜(见 GetChart), 上一个周期没有播放记录时视为空榜, 若 date 所在周期的排行榜不存在会返回 nil
+func (c *Client) GetChartMovements(dbc dbClient, period, entity string, date time.Time) ([]ChartMovement, error) {
+	current, err := c.GetChart(dbc, period, entity, date)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, nil
+	}
+
+	start := getPeriodStart(period, date)
+	previousStart := getPeriodStart(period, start.AddDate(0, 0, -1))
+
+	previous, err := c.GetChart(dbc, period, entity, previousStart)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := c.GetChartDates(dbc, period, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	earlier := map[string]bool{}
+
+	for _, d := range dates {
+		if d >= previousStart.Format(time.DateOnly) {
+			break
+		}
+
+		t, err := time.ParseInLocation(time.DateOnly, d, time.Local)
+		if err != nil {
+			return nil, err
+		}
+
+		tops, err := c.GetChart(dbc, period, entity, t)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, top := range tops {
+			earlier[top.ID] = true
+		}
+	}
+
+	return diffRankings(topsToIDs(previous), topsToIDs(current), earlier), nil
+}
@@ -0,0 +1,276 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// 按播放记录统计的排行榜, 与 Spotify 提供的 monthly-top-* 等不同, 周期结束后才会统计, 存储后不再修改, 即使之后导入或修改了播放记录
+// 存储在数据库字典 {周期}-chart-{类型} 中, 如 daily-chart-tracks, 以周期第一天的 time.DateOnly 格式为键, []Tops 的 JSON 为值
+const (
+	ChartDaily   = "daily"
+	ChartWeekly  = "weekly" // 从周一开始
+	ChartMonthly = "monthly"
+	ChartYearly  = "yearly"
+)
+
+const (
+	ChartEntityTracks  = "tracks"
+	ChartEntityArtists = "artists"
+	ChartEntityAlbums  = "albums"
+)
+
+var (
+	chartPeriods  = []string{ChartDaily, ChartWeekly, ChartMonthly, ChartYearly}
+	chartEntities = []string{ChartEntityTracks, ChartEntityArtists, ChartEntityAlbums}
+)
+
+var errInvalidChart = errors.New("无效的排行榜周期或类型")
+
+// chartLimit 是每个排行榜存储的最大条数
+const chartLimit = 100
+
+func chartKey(period, entity string) string {
+	return period + "-chart-" + entity
+}
+
+func isValidChart(period, entity string) bool {
+	return slices.Contains(chartPeriods, period) && slices.Contains(chartEntities, entity)
+}
+
+// getPeriodStart 返回 t 所在周期的第一天
+func getPeriodStart(period string, t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	switch period {
+	case ChartWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case ChartMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+	case ChartYearly:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.Local)
+	}
+
+	return day
+}
+
+// getNextPeriodStart 返回下一个周期的第一天, start 应为周期的第一天
+func getNextPeriodStart(period string, start time.Time) time.Time {
+	switch period {
+	case ChartWeekly:
+		return start.AddDate(0, 0, 7)
+	case ChartMonthly:
+		return start.AddDate(0, 1, 0)
+	case ChartYearly:
+		return start.AddDate(1, 0, 0)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+// getPlaybackRangeWithinATime 获取一段时间内的收听量(包括t1和t2), 与 GetPlaybackRangeDuringATime 不同, t1 与 t2 当天可以没有数据, 若整段时间都没有数据会返回 nil
+func (c *Client) getPlaybackRangeWithinATime(dbc dbClient, t1, t2 time.Time) (*PlaybackRange, error) {
+	var start, end *PlaybackRange
+
+	for t := t1; !t.After(t2); t = t.AddDate(0, 0, 1) {
+		r, err := c.GetPlaybackRangeOnADay(dbc, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if r != nil {
+			start = r
+			break
+		}
+	}
+
+	if start == nil {
+		return nil, nil
+	}
+
+	for t := t2; !t.Before(t1); t = t.AddDate(0, 0, -1) {
+		r, err := c.GetPlaybackRangeOnADay(dbc, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if r != nil {
+			end = r
+			break
+		}
+	}
+
+	return &PlaybackRange{start.Start, end.End}, nil
+}
+
+// GetChartsDuringATime 统计一段时间内(包括t1和t2)的曲目 艺术家 专辑排行, 只有 ID 与收听量, 键为 ChartEntity*, 若整段时间都没有数据会返回nil, limit为0则不限制
+// 与 GetTops 的统计相同, 需要多种排行时只读取一次播放记录
+func (c *Client) GetChartsDuringATime(dbc dbClient, t1, t2 time.Time, limit int) (map[string][]Tops, error) {
+	return c.computeCharts(dbc, t1, t2, limit)
+}
+
+// computeCharts 统计一段时间内的曲目 艺术家 专辑排行, 键为 ChartEntity*, 若整段时间都没有数据会返回 nil
+func (c *Client) computeCharts(dbc dbClient, t1, t2 time.Time, limit int) (map[string][]Tops, error) {
+	r, err := c.getPlaybackRangeWithinATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, nil
+	}
+
+	ph, err := c.GetPlaybackHistory(dbc, int64(r.Start), int64(r.End))
+	if err != nil {
+		return nil, err
+	}
+
+	trackCounts := map[string]int{}
+	artistCounts := map[string]int{}
+	albumCounts := map[string]int{}
+
+	for _, track := range ph {
+		trackCounts[track.ID]++
+		albumCounts[track.Album.ID]++
+
+		for _, artist := range track.Artists {
+			artistCounts[artist.ID]++
+		}
+	}
+
+	return map[string][]Tops{
+		ChartEntityTracks:  sortTops(trackCounts, limit),
+		ChartEntityArtists: sortTops(artistCounts, limit),
+		ChartEntityAlbums:  sortTops(albumCounts, limit),
+	}, nil
+}
+
+// saveCharts 统计并存储所有已经结束但尚未统计的周期的排行榜
+// 只有在周期结束之后成功获取过最近播放才视为结束, 以免周期最后的播放记录尚未追加
+func (c *Client) saveCharts(dbc dbClient) error {
+	first, err := dbc.GetSliceByIndex("playback-history", 0)
+	if err != nil {
+		return err
+	}
+
+	if first == "" {
+		return nil
+	}
+
+	firstPlayedAt, err := getTime(time.DateOnly, first)
+	if err != nil {
+		return err
+	}
+
+	ingestedUntil, err := getRecentlyPlayedTime(dbc)
+	if err != nil {
+		return err
+	}
+
+	for _, period := range chartPeriods {
+		lastSaved, err := dbc.GetMapStr("updated-times", period+"-charts")
+		if err != nil {
+			return err
+		}
+
+		start := getPeriodStart(period, time.Date(firstPlayedAt.Year(), firstPlayedAt.Month(), firstPlayedAt.Day(), 0, 0, 0, 0, time.Local))
+
+		if lastSaved != "" {
+			t, err := time.ParseInLocation(time.DateOnly, lastSaved, time.Local)
+			if err != nil {
+				return err
+			}
+
+			start = getNextPeriodStart(period, t)
+		}
+
+		for next := getNextPeriodStart(period, start); !next.After(ingestedUntil); start, next = next, getNextPeriodStart(period, next) {
+			err = c.saveChartsOfAPeriod(dbc, period, start, next.AddDate(0, 0, -1))
+			if err != nil {
+				return err
+			}
+
+			err = dbc.SetMap("updated-times", period+"-charts", start.Format(time.DateOnly))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// saveChartsOfAPeriod 存储一个周期的排行榜, 已经存储的不会被覆盖, 没有播放记录的周期不会存储
+func (c *Client) saveChartsOfAPeriod(dbc dbClient, period string, start, end time.Time) error {
+	field := start.Format(time.DateOnly)
+
+	charts, err := c.computeCharts(dbc, start, end, chartLimit)
+	if err != nil {
+		return err
+	}
+
+	for entity, tops := range charts {
+		exists, err := dbc.CheckIfMapFieldExists(chartKey(period, entity), field)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		j, err := json.Marshal(tops)
+		if err != nil {
+			return err
+		}
+
+		err = dbc.SetMap(chartKey(period, entity), field, string(j))
+		if err != nil {
+			return err
+		}
+	}
+
+	if charts != nil {
+		slog.Debug("排行榜保存成功", "周期", period, "日期", field)
+	}
+
+	return nil
+}
+
+// GetChart 返回 date 所在周期的排行榜, period 应使用 ChartDaily 等, entity 应使用 ChartEntityTracks 等, 若周期尚未结束或没有播放记录会返回 nil
+func (c *Client) GetChart(dbc dbClient, period, entity string, date time.Time) ([]Tops, error) {
+	if !isValidChart(period, entity) {
+		return nil, errInvalidChart
+	}
+
+	s, err := dbc.GetMapStr(chartKey(period, entity), getPeriodStart(period, date).Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	var tops []Tops
+
+	err = json.Unmarshal([]byte(s), &tops)
+	if err != nil {
+		return nil, err
+	}
+
+	return tops, nil
+}
+
+// GetChartDates 返回已存储的排行榜的周期第一天, 按时间排序
+func (c *Client) GetChartDates(dbc dbClient, period, entity string) ([]string, error) {
+	if !isValidChart(period, entity) {
+		return nil, errInvalidChart
+	}
+
+	return getSnapshotDates(dbc, chartKey(period, entity))
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/HenTaku321/spotify-insights-go"
+	"github.com/HenTaku321/spotify-insights-go/insightspb"
+	"google.golang.org/grpc"
+)
+
+// store 与 spotify 包的数据库接口相同, 用于在 valkeyDB 与 spotify.TraceDB 的返回值之间切换
+type store interface {
+	SetString(key string, value string, ex *time.Duration) error
+	GetString(key string) (string, error)
+	SetMap(key, field, value string) error
+	GetMapStr(key, field string) (string, error)
+	GetMapInt64(key, field string) (int64, error)
+	GetMapLen(key string) (int64, error)
+	GetMapAll(key string) (map[string]string, error)
+	CheckIfMapFieldExists(key, field string) (bool, error)
+	AppendSlice(key string, value []string) error
+	GetSlice(key string, start, stop int64) ([]string, error)
+	GetSliceByIndex(key string, index int64) (string, error)
+	GetSliceLen(key string) (int64, error)
+	Delete(key string) error
+}
+
+func runCollector(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	httpAddr := fs.String("http", "", "JSON 接口 /metrics 与网页仪表盘(/dashboard/)的监听地址, 如 :8081, 为空时不启动")
+	playerState := fs.Duration("player-state", 0, "播放器状态的采样间隔, 如 30s, 为 0 时不采样")
+	live := fs.Duration("live", 0, "正在播放的轮询间隔, 用于 /events, 为 0 时不轮询")
+	webhookInterval := fs.Duration("webhook-interval", 0, "发送 Webhook 的间隔, 为 0 时使用默认值, 配置文件中没有 webhooks 时不发送")
+	trace := fs.String("trace", "", "OpenTelemetry exporter, otlp 或 stdout, 为空时不记录")
+	grpcAddr := fs.String("grpc", "", "gRPC 服务(InsightsService)的监听地址, 如 :9090, 为空时不启动")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientInteractive)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	a.c.CorrectionStrategies = a.cfg.CorrectionStrategies
+	a.c.Webhooks = a.cfg.Webhooks
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var dbc store = a.db
+
+	if *trace != "" {
+		shutdown, err := spotify.SetupTracing(ctx, *trace)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			if err := shutdown(shutdownCtx); err != nil {
+				slog.Warn("关闭 OpenTelemetry 失败", "error", err)
+			}
+		}()
+
+		dbc = spotify.TraceDB(a.db)
+	}
+
+	if *httpAddr != "" {
+		server := &http.Server{Addr: *httpAddr, Handler: a.c.NewHandler(dbc)}
+
+		go func() {
+			slog.Info("JSON 接口已启动", "地址", *httpAddr)
+
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP 服务器错误", "error", err)
+				stop()
+			}
+		}()
+		defer server.Close()
+	}
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+
+		server := grpc.NewServer()
+		insightspb.RegisterInsightsServiceServer(server, a.c.GRPCService(dbc))
+
+		go func() {
+			slog.Info("gRPC 服务已启动", "地址", *grpcAddr)
+
+			if err := server.Serve(lis); err != nil {
+				slog.Error("gRPC 服务器错误", "error", err)
+				stop()
+			}
+		}()
+		defer server.Stop()
+	}
+
+	if *playerState > 0 {
+		go a.c.RunPlayerStateCollector(dbc, *playerState)
+	}
+
+	if *live > 0 {
+		go a.c.RunLivePoller(dbc, *live)
+	}
+
+	if len(a.c.Webhooks) > 0 {
+		go a.c.RunWebhookDispatcher(dbc, *webhookInterval)
+	}
+
+	go a.c.Run(dbc)
+
+	<-ctx.Done()
+	slog.Info("收到退出信号, 程序退出")
+
+	return nil
+}
+
+func runLogin(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientInteractive)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	user, err := a.c.C.CurrentUser(a.c.Ctx)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"USER", "ID"}}
+	t.add(user.DisplayName, user.ID)
+
+	return a.print(map[string]string{"display_name": user.DisplayName, "id": user.ID}, t)
+}
+
+func runHistory(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	limit := fs.Int64("limit", 20, "显示的数量, 单集会被跳过, 数量可能更少")
+	offset := fs.Int64("offset", 0, "跳过的数量")
+	asc := fs.Bool("asc", false, "从最早开始")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *limit < 1 || *offset < 0 {
+		return usageErrorf("-limit 应大于 0, -offset 不能小于 0")
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	total, err := a.c.GetTotalPlaybackHistoryCount(a.db)
+	if err != nil {
+		return err
+	}
+
+	items := []spotify.PlayedTrack{}
+
+	if *offset < total {
+		start, stop := *offset, min(*offset+*limit, total)-1
+		if !*asc {
+			start, stop = total-1-stop, total-1-start
+		}
+
+		ph, err := a.c.GetPlaybackHistory(a.db, start, stop)
+		if err != nil {
+			return err
+		}
+
+		if !*asc {
+			slices.Reverse(ph)
+		}
+
+		items = append(items, ph...)
+	}
+
+	t := &table{header: []string{"PLAYED AT", "TRACK", "ARTISTS", "ALBUM"}}
+	for _, item := range items {
+		t.add(item.PlayedAt, item.Name, artistNames(item.Artists), item.Album.Name)
+	}
+
+	return a.print(items, t)
+}
+
+// parseDateFlag 解析 time.DateOnly 格式的日期
+func parseDateFlag(name, s string) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, usageErrorf("-%s 的日期格式应为 %s", name, time.DateOnly)
+	}
+	return t, nil
+}
+
+func runTop(cmd *command, args []string) error {
+	// 实体可以写在参数之前, 如 top tracks -limit 10
+	var entity string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		entity, args = args[0], args[1:]
+	}
+
+	tn := time.Now()
+
+	fs, g := newFlagSet(cmd)
+	from := fs.String("from", tn.AddDate(0, 0, -29).Format(time.DateOnly), "开始日期, 格式为 "+time.DateOnly)
+	to := fs.String("to", tn.Format(time.DateOnly), "结束日期, 格式为 "+time.DateOnly)
+	limit := fs.Int("limit", 10, "显示的数量, 为 0 时不限制")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if entity == "" {
+		entity = fs.Arg(0)
+	}
+
+	if entity != spotify.ChartEntityTracks && entity != spotify.ChartEntityArtists && entity != spotify.ChartEntityAlbums {
+		return usageErrorf("应指定 tracks, artists 或 albums")
+	}
+
+	if *limit < 0 {
+		return usageErrorf("-limit 不能小于 0")
+	}
+
+	t1, err := parseDateFlag("from", *from)
+	if err != nil {
+		return err
+	}
+
+	t2, err := parseDateFlag("to", *to)
+	if err != nil {
+		return err
+	}
+
+	if t2.Before(t1) {
+		return usageErrorf("-to 不能早于 -from")
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	items, err := a.c.GetTops(a.db, entity, t1, t2, *limit)
+	if err != nil {
+		return err
+	}
+
+	if items == nil {
+		items = []spotify.TopItem{}
+	}
+
+	t := &table{header: []string{"#", "PLAYS", "NAME", "ARTISTS", "ID"}}
+
+	for i, item := range items {
+		var name, artists string
+
+		switch v := item.Item.(type) {
+		case *spotify.Track:
+			name, artists = v.Name, artistNames(v.Artists)
+		case *spotify.Album:
+			name, artists = v.Name, artistNames(v.Artists)
+		case *spotify.Artist:
+			name = v.Name
+		}
+
+		t.add(i+1, item.Count, name, artists, item.ID)
+	}
+
+	return a.print(items, t)
+}
+
+func runHourly(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	counts, err := a.c.GetHourlyPlayBackCounts(a.db)
+	if err != nil {
+		return err
+	}
+
+	hours := make([]int, 0, len(counts))
+	for hour := range counts {
+		hours = append(hours, hour)
+	}
+	slices.Sort(hours)
+
+	t := &table{header: []string{"HOUR", "PLAYS"}}
+	for _, hour := range hours {
+		t.add(fmt.Sprintf("%02d:00", hour), counts[hour])
+	}
+
+	return a.print(counts, t)
+}
+
+func runNow(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientRequired)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	cp, err := a.c.GetCurrentlyPlayingTrack(a.db)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TYPE", "NAME", "BY", "DURATION"}}
+
+	switch {
+	case cp == nil:
+		t = &table{rows: [][]string{{"当前没有在播放"}}}
+	case cp.Episode != nil:
+		t.add(cp.Type, cp.Episode.Name, cp.Episode.Show.Name, cp.TimeStamp)
+	default:
+		t.add(cp.Type, cp.Name, artistNames(cp.Artists), cp.TimeStamp)
+	}
+
+	return a.print(spotify.NowPlaying{IsPlaying: cp != nil, Item: cp}, t)
+}
+
+func runDashboard(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	interval := fs.Duration("interval", time.Second*10, "刷新间隔, 只按此间隔从数据库读取, run 保存的新播放记录在下一次刷新时显示")
+	logFile := fs.String("log-file", "", "日志文件路径, 为空时不输出日志, 以免破坏界面")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *interval <= 0 {
+		return usageErrorf("-interval 应大于 0")
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	logOutput := io.Discard
+
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		logOutput = f
+	}
+
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(a.cfg.LogLevel))
+	slog.SetDefault(slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: level})))
+
+	return showDashboard(a.c, a.db, *interval)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/HenTaku321/spotify-insights-go"
+)
+
+// config 的优先级从低到高为 默认值 配置文件 环境变量 命令行参数
+type config struct {
+	ValkeyAddr     string `json:"valkey_addr"`     // VALKEY_ADDR, -addr
+	ValkeyPassword string `json:"valkey_password"` // VALKEY_PASSWD
+	ValkeyDB       int    `json:"valkey_db"`       // VALKEY_DB, -db
+	ValkeyTLS      bool   `json:"valkey_tls"`      // VALKEY_TLS, -tls
+	SpotifyKey     string `json:"spotify_key"`     // SPOTIFY_KEY, 用于加密 Spotify 令牌
+	Output         string `json:"output"`          // SPOTIFY_INSIGHTS_OUTPUT, -o, table 或 json
+	LogLevel       string `json:"log_level"`       // SPOTIFY_INSIGHTS_LOG_LEVEL, -log-level, debug info warn error
+
+	// 以下只能在配置文件中设置, 用于 run
+	CorrectionStrategies []string          `json:"correction_strategies"`
+	Webhooks             []spotify.Webhook `json:"webhooks"`
+}
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func defaultConfig() *config {
+	return &config{
+		ValkeyAddr: "127.0.0.1:6379",
+		Output:     outputTable,
+		LogLevel:   "info",
+	}
+}
+
+// globalFlags 是所有命令共用的参数, 未设置的参数不会覆盖配置文件与环境变量
+type globalFlags struct {
+	fs *flag.FlagSet
+
+	configPath string
+	addr       string
+	db         int
+	tls        bool
+	output     string
+	logLevel   string
+}
+
+func addGlobalFlags(fs *flag.FlagSet) *globalFlags {
+	g := &globalFlags{fs: fs}
+
+	fs.StringVar(&g.configPath, "config", "", "配置文件路径, 默认为 $SPOTIFY_INSIGHTS_CONFIG 或用户配置目录下的 spotify-insights/config.json")
+	fs.StringVar(&g.addr, "addr", "", "Valkey 地址, 如 127.0.0.1:6379")
+	fs.IntVar(&g.db, "db", 0, "Valkey 数据库编号")
+	fs.BoolVar(&g.tls, "tls", false, "使用 TLS 连接 Valkey")
+	fs.StringVar(&g.output, "o", "", "输出格式, table 或 json")
+	fs.StringVar(&g.logLevel, "log-level", "", "日志级别, debug info warn 或 error")
+
+	return g
+}
+
+// load 依次读取配置文件 环境变量与命令行参数, 应在 fs.Parse 之后调用
+func (g *globalFlags) load() (*config, error) {
+	cfg := defaultConfig()
+
+	path, explicit := g.configPath, g.configPath != ""
+	if !explicit {
+		path, explicit = os.Getenv("SPOTIFY_INSIGHTS_CONFIG"), os.Getenv("SPOTIFY_INSIGHTS_CONFIG") != ""
+	}
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "spotify-insights", "config.json")
+		}
+	}
+
+	if path != "" {
+		err := readConfigFile(path, cfg)
+		if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+			return nil, fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+		}
+	}
+
+	err := applyEnv(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	g.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.ValkeyAddr = g.addr
+		case "db":
+			cfg.ValkeyDB = g.db
+		case "tls":
+			cfg.ValkeyTLS = g.tls
+		case "o":
+			cfg.Output = g.output
+		case "log-level":
+			cfg.LogLevel = g.logLevel
+		}
+	})
+
+	if cfg.Output != outputTable && cfg.Output != outputJSON {
+		return nil, usageErrorf("输出格式应为 table 或 json, 得到 %q", cfg.Output)
+	}
+
+	var level slog.Level
+	err = level.UnmarshalText([]byte(cfg.LogLevel))
+	if err != nil {
+		return nil, usageErrorf("无效的日志级别 %q", cfg.LogLevel)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	return cfg, nil
+}
+
+func readConfigFile(path string, cfg *config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, cfg)
+}
+
+func applyEnv(cfg *config) error {
+	if s := os.Getenv("VALKEY_ADDR"); s != "" {
+		cfg.ValkeyAddr = s
+	}
+	if s := os.Getenv("VALKEY_PASSWD"); s != "" {
+		cfg.ValkeyPassword = s
+	}
+	if s := os.Getenv("VALKEY_DB"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("环境变量 VALKEY_DB 应为整数: %w", err)
+		}
+		cfg.ValkeyDB = n
+	}
+	if s := os.Getenv("VALKEY_TLS"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("环境变量 VALKEY_TLS 应为布尔值: %w", err)
+		}
+		cfg.ValkeyTLS = b
+	}
+	if s := os.Getenv("SPOTIFY_KEY"); s != "" {
+		cfg.SpotifyKey = s
+	}
+	if s := os.Getenv("SPOTIFY_INSIGHTS_OUTPUT"); s != "" {
+		cfg.Output = s
+	}
+	if s := os.Getenv("SPOTIFY_INSIGHTS_LOG_LEVEL"); s != "" {
+		cfg.LogLevel = s
+	}
+
+	return nil
+}
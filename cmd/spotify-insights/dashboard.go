@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HenTaku321/spotify-insights-go"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	dashboardTopLimit        = 10
+	dashboardRecentLimit     = 100
+	dashboardHistogramHeight = 6
+)
+
+// dashboardItem 是排行或播放记录中的一行, 只读模式下缺少信息时 Name 为 ID
+type dashboardItem struct {
+	Name     string
+	By       string // 艺术家或播客名称
+	Count    int
+	PlayedAt string
+}
+
+// dashboardData 是一次刷新读取到的数据
+type dashboardData struct {
+	nowPlaying *spotify.CurrentlyPlaying
+	nowErr     error
+	today      int
+	hourly     map[int]int
+	topArtists []dashboardItem
+	topTracks  []dashboardItem
+	topsErr    error
+	recent     []dashboardItem
+	updatedAt  time.Time
+}
+
+type dashboardDataMsg struct {
+	data *dashboardData
+	err  error
+}
+
+type dashboardTickMsg struct{}
+
+// showDashboard 在终端中显示正在播放 今天的收听量 每小时收听量 本月的热门艺术家与曲目以及最近的播放记录
+// 只从数据库读取, 不与 run 通信, 因此只按 interval 刷新, 新的播放记录在 run 保存后的下一次刷新时显示
+// c.C 为 nil 时以只读模式运行, 不显示正在播放, 数据库中缺少的曲目信息以 ID 显示
+// 运行期间写入终端的日志会破坏界面, 应将 slog 输出到文件或丢弃, 按 q 退出
+func showDashboard(c *spotify.Client, dbc store, interval time.Duration) error {
+	m := &dashboardModel{c: c, dbc: dbc, interval: interval}
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(c.Ctx)).Run()
+	if errors.Is(err, tea.ErrProgramKilled) && c.Ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// loadDashboard 读取界面需要的所有数据, 只读模式下缺少曲目信息不会返回错误
+func loadDashboard(c *spotify.Client, dbc store) (*dashboardData, error) {
+	tn := time.Now()
+	data := &dashboardData{updatedAt: tn}
+
+	if c.C == nil {
+		data.nowErr = spotify.ErrNoSpotifyClient
+	} else {
+		data.nowPlaying, data.nowErr = c.GetCurrentlyPlayingTrack(dbc)
+	}
+
+	today, err := c.GetPlaybackRangeOnADay(dbc, tn)
+	if err != nil {
+		return nil, err
+	}
+	if today != nil {
+		data.today = today.End - today.Start + 1
+	}
+
+	data.hourly, err = c.GetHourlyPlayBackCounts(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	data.topArtists, data.topTracks, err = loadDashboardTops(c, dbc, tn)
+	if errors.Is(err, spotify.ErrNoSpotifyClient) {
+		// 本月的播放记录中有数据库中缺少信息的曲目, 只读模式下无法统计
+		data.topsErr = err
+	} else if err != nil {
+		return nil, err
+	}
+
+	data.recent, err = loadDashboardRecent(c, dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func loadDashboardTops(c *spotify.Client, dbc store, tn time.Time) (artists []dashboardItem, tracks []dashboardItem, err error) {
+	firstDay := time.Date(tn.Year(), tn.Month(), 1, 0, 0, 0, 0, tn.Location())
+
+	charts, err := c.GetChartsDuringATime(dbc, firstDay, tn, dashboardTopLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, top := range charts[spotify.ChartEntityArtists] {
+		item := dashboardItem{Name: top.ID, Count: top.Count}
+
+		artist, err := c.GetArtist(dbc, top.ID)
+		if err != nil && !errors.Is(err, spotify.ErrNoSpotifyClient) {
+			return nil, nil, err
+		}
+		if artist != nil {
+			item.Name = artist.Name
+		}
+
+		artists = append(artists, item)
+	}
+
+	for _, top := range charts[spotify.ChartEntityTracks] {
+		item, err := dashboardTrack(c, dbc, top.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		item.Count = top.Count
+
+		tracks = append(tracks, item)
+	}
+
+	return artists, tracks, nil
+}
+
+// loadDashboardRecent 返回最近的播放记录, 从最新开始, 包括单集
+func loadDashboardRecent(c *spotify.Client, dbc store) ([]dashboardItem, error) {
+	total, err := c.GetTotalPlaybackHistoryCount(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	if total == 0 {
+		return nil, nil
+	}
+
+	entries, err := c.GetPlaybackEntries(dbc, max(total-dashboardRecentLimit, 0), total-1)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dashboardItem, 0, len(entries))
+
+	for _, entry := range slices.Backward(entries) {
+		var item dashboardItem
+
+		if entry.Type == spotify.EntryTypeEpisode {
+			item = dashboardItem{Name: entry.ID}
+
+			episode, err := c.GetEpisode(dbc, entry.ID)
+			if err != nil && !errors.Is(err, spotify.ErrNoSpotifyClient) {
+				return nil, err
+			}
+			if episode != nil {
+				item.Name, item.By = episode.Name, episode.Show.Name
+			}
+		} else {
+			item, err = dashboardTrack(c, dbc, entry.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		item.PlayedAt = entry.PlayedAt
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func dashboardTrack(c *spotify.Client, dbc store, id string) (dashboardItem, error) {
+	item := dashboardItem{Name: id}
+
+	track, err := c.GetTrack(dbc, id)
+	if err != nil && !errors.Is(err, spotify.ErrNoSpotifyClient) {
+		return item, err
+	}
+
+	if track != nil {
+		item.Name, item.By = track.Name, artistNames(track.Artists)
+	}
+
+	return item, nil
+}
+
+type dashboardModel struct {
+	c        *spotify.Client
+	dbc      store
+	interval time.Duration
+
+	data    *dashboardData
+	err     error // 最近一次刷新的错误, 保留上一次的数据
+	loading bool
+	scroll  int
+	width   int
+	height  int
+}
+
+func (m *dashboardModel) load() tea.Cmd {
+	m.loading = true
+
+	return func() tea.Msg {
+		data, err := loadDashboard(m.c, m.dbc)
+		return dashboardDataMsg{data, err}
+	}
+}
+
+func (m *dashboardModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg {
+		return dashboardTickMsg{}
+	})
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return tea.Batch(m.load(), m.tick())
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "r":
+			if !m.loading {
+				return m, m.load()
+			}
+		case "up", "k":
+			m.scroll--
+		case "down", "j":
+			m.scroll++
+		case "pgup":
+			m.scroll -= m.historyRows()
+		case "pgdown", " ":
+			m.scroll += m.historyRows()
+		case "home", "g":
+			m.scroll = 0
+		}
+		m.clampScroll()
+
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.clampScroll()
+
+	case dashboardDataMsg:
+		m.loading = false
+		m.err = msg.err
+		if msg.err == nil {
+			m.data = msg.data
+		}
+		m.clampScroll()
+
+	case dashboardTickMsg:
+		cmds := []tea.Cmd{m.tick()}
+		if !m.loading {
+			cmds = append(cmds, m.load())
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	return m, nil
+}
+
+var (
+	dashboardTitleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("10"))
+	dashboardHeadingStyle = lipgloss.NewStyle().Bold(true)
+	dashboardDimStyle     = lipgloss.NewStyle().Faint(true)
+	dashboardErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	dashboardBarStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+)
+
+// dashboardFixedRows 是最近播放以外的部分占用的行数
+const dashboardFixedRows = 2 + 3 + dashboardHistogramHeight + 2 + dashboardTopLimit + 3 + 2
+
+func (m *dashboardModel) historyRows() int {
+	return max(m.height-dashboardFixedRows, 3)
+}
+
+func (m *dashboardModel) clampScroll() {
+	n := 0
+	if m.data != nil {
+		n = len(m.data.recent)
+	}
+	m.scroll = max(min(m.scroll, n-m.historyRows()), 0)
+}
+
+func (m *dashboardModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	width := max(m.width, 40)
+	line := lipgloss.NewStyle().MaxWidth(width)
+
+	var b strings.Builder
+
+	title := dashboardTitleStyle.Render("Spotify Insights")
+	if m.c.C == nil {
+		title += dashboardDimStyle.Render("  只读模式")
+	}
+	if m.data != nil {
+		title += dashboardDimStyle.Render("  更新于 " + m.data.updatedAt.Format(time.TimeOnly))
+	}
+	if m.loading {
+		title += dashboardDimStyle.Render("  刷新中...")
+	}
+	b.WriteString(line.Render(title) + "\n")
+
+	if m.err != nil {
+		b.WriteString(line.Render(dashboardErrorStyle.Render("刷新失败: "+m.err.Error())) + "\n")
+	} else {
+		b.WriteString("\n")
+	}
+
+	if m.data == nil {
+		b.WriteString("正在加载...\n")
+		return b.String()
+	}
+
+	d := m.data
+
+	b.WriteString(line.Render(dashboardHeadingStyle.Render("正在播放  ")+renderNowPlaying(d)) + "\n")
+	b.WriteString(line.Render(dashboardHeadingStyle.Render("今天      ")+strconv.Itoa(d.today)+" 次播放") + "\n\n")
+
+	b.WriteString(renderHourlyHistogram(d.hourly) + "\n")
+
+	colWidth := (width - 2) / 2
+	artists := renderTopList("本月热门艺术家", d.topArtists, d.topsErr, colWidth)
+	tracks := renderTopList("本月热门曲目", d.topTracks, d.topsErr, colWidth)
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, artists, "  ", tracks) + "\n\n")
+
+	b.WriteString(dashboardHeadingStyle.Render("最近播放") + dashboardDimStyle.Render("  ↑↓ 滚动  r 刷新  q 退出") + "\n")
+
+	rows := m.historyRows()
+	end := min(m.scroll+rows, len(d.recent))
+
+	if len(d.recent) == 0 {
+		b.WriteString(dashboardDimStyle.Render("没有播放记录") + "\n")
+	}
+
+	for _, item := range d.recent[m.scroll:end] {
+		s := dashboardDimStyle.Render(item.PlayedAt) + "  " + item.Name
+		if item.By != "" {
+			s += dashboardDimStyle.Render(" - " + item.By)
+		}
+		b.WriteString(line.Render(s) + "\n")
+	}
+
+	return b.String()
+}
+
+func renderNowPlaying(d *dashboardData) string {
+	switch {
+	case errors.Is(d.nowErr, spotify.ErrNoSpotifyClient):
+		return dashboardDimStyle.Render("只读模式下无法获取")
+	case d.nowErr != nil:
+		return dashboardErrorStyle.Render("获取失败: " + d.nowErr.Error())
+	case d.nowPlaying == nil:
+		return dashboardDimStyle.Render("没有在播放")
+	case d.nowPlaying.Episode != nil:
+		return d.nowPlaying.Episode.Name + dashboardDimStyle.Render(" - "+d.nowPlaying.Episode.Show.Name)
+	}
+
+	return d.nowPlaying.Name + dashboardDimStyle.Render(" - "+artistNames(d.nowPlaying.Artists)+" · "+d.nowPlaying.Album.Name)
+}
+
+// renderHourlyHistogram 以每小时 3 列的柱状图显示 0 到 23 点的收听量
+func renderHourlyHistogram(hourly map[int]int) string {
+	blocks := []rune(" ▁▂▃▄▅▆▇█")
+
+	maxCount := 0
+	for hour := 0; hour < 24; hour++ {
+		maxCount = max(maxCount, hourly[hour])
+	}
+
+	var b strings.Builder
+
+	b.WriteString(dashboardHeadingStyle.Render("每小时收听量") + dashboardDimStyle.Render(fmt.Sprintf("  最多 %d 次", maxCount)) + "\n")
+
+	for row := dashboardHistogramHeight - 1; row >= 0; row-- {
+		var s strings.Builder
+
+		for hour := 0; hour < 24; hour++ {
+			// 柱的高度, 以八分之一行为单位
+			eighths := 0
+			if maxCount > 0 {
+				eighths = hourly[hour] * dashboardHistogramHeight * 8 / maxCount
+			}
+
+			level := min(max(eighths-row*8, 0), 8)
+			s.WriteString(strings.Repeat(string(blocks[level]), 2) + " ")
+		}
+
+		b.WriteString(dashboardBarStyle.Render(s.String()) + "\n")
+	}
+
+	for hour := 0; hour < 24; hour += 3 {
+		b.WriteString(dashboardDimStyle.Render(fmt.Sprintf("%-9s", fmt.Sprintf("%02d", hour))))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func renderTopList(heading string, items []dashboardItem, err error, width int) string {
+	line := lipgloss.NewStyle().MaxWidth(width)
+
+	lines := []string{dashboardHeadingStyle.Render(heading)}
+
+	switch {
+	case err != nil:
+		lines = append(lines, dashboardDimStyle.Render("只读模式下缺少曲目信息, 无法统计"))
+	case len(items) == 0:
+		lines = append(lines, dashboardDimStyle.Render("本月没有播放记录"))
+	}
+
+	for i, item := range items {
+		s := fmt.Sprintf("%2d. %s", i+1, item.Name)
+		if item.By != "" {
+			s += dashboardDimStyle.Render(" - " + item.By)
+		}
+		s += dashboardDimStyle.Render(fmt.Sprintf(" (%d)", item.Count))
+
+		lines = append(lines, line.Render(s))
+	}
+
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
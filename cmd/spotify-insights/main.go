@@ -0,0 +1,195 @@
+// spotify-insights 是收集 查询与维护 Spotify 收听数据的命令行工具
+//
+// 用法:
+//
+//	spotify-insights <命令> [参数]
+//
+// 配置的优先级从低到高为 默认值 配置文件 环境变量 命令行参数, 见 config
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/HenTaku321/spotify-insights-go"
+)
+
+type command struct {
+	name    string
+	args    string // 用法中命令名之后的部分
+	summary string
+	run     func(cmd *command, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"run", "[参数]", "运行收集程序(定时保存最近播放 排行榜等), 首次运行会进行网页授权", runCollector},
+		{"login", "[参数]", "进行网页授权并保存 Spotify 令牌, 已登录时显示当前用户", runLogin},
+		{"history", "[参数]", "显示播放记录, 默认从最新开始", runHistory},
+		{"top", "tracks|artists|albums [参数]", "显示一段时间内(包括 -from 和 -to)的热门曲目 艺术家或专辑", runTop},
+		{"hourly", "[参数]", "显示每个小时的收听量", runHourly},
+		{"now", "[参数]", "显示正在播放的曲目或单集", runNow},
+		{"dashboard", "[参数]", "在终端中显示定时刷新的仪表盘, 没有已保存的令牌时以只读模式运行", runDashboard},
+		{"rebuild", "[参数]", "按完整的播放记录重新统计收听量, 运行期间应停止 run", runRebuild},
+		{"check", "[参数]", "检查播放记录的顺序与每日收听量统计, 并列出可能丢失播放记录的时间段, 有问题时退出码为 1", runCheck},
+		{"import", "spotify|lastfm [参数] 文件...", "导入 Spotify 数据导出(Extended streaming history)或 Last.fm 收听记录导出, 运行期间应停止 run", runImport},
+		{"export", "[参数]", "导出播放记录为 JSON 或 CSV", runExport},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage(os.Stdout)
+		return
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == name {
+			cmd = c
+		}
+	}
+
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	err := cmd.run(cmd, os.Args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	var ue *usageError
+	if errors.As(err, &ue) {
+		fmt.Fprintf(os.Stderr, "%s\n\n用法: spotify-insights %s %s\n", err, cmd.name, cmd.args)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "用法: spotify-insights <命令> [参数]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "命令:")
+
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "运行 spotify-insights <命令> -h 查看命令的参数")
+	fmt.Fprintln(w, "环境变量: VALKEY_ADDR VALKEY_PASSWD VALKEY_DB VALKEY_TLS SPOTIFY_KEY SPOTIFY_INSIGHTS_CONFIG SPOTIFY_INSIGHTS_OUTPUT SPOTIFY_INSIGHTS_LOG_LEVEL")
+}
+
+// usageError 表示参数错误, 退出码为 2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, a ...any) error {
+	return &usageError{fmt.Sprintf(format, a...)}
+}
+
+// newFlagSet 返回带有共用参数的 FlagSet, 参数错误时只返回错误, 由 main 处理
+func newFlagSet(cmd *command) (*flag.FlagSet, *globalFlags) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: spotify-insights %s %s\n\n%s\n\n参数:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs, addGlobalFlags(fs)
+}
+
+// parseFlags 解析参数, 错误信息已由 flag 输出, 因此返回的错误不再重复
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	return err
+}
+
+// clientMode 决定 setup 如何创建 Spotify 客户端
+type clientMode int
+
+const (
+	clientOptional    clientMode = iota // 使用已保存的令牌, 没有令牌时以只读模式运行
+	clientRequired                      // 必须有已保存的令牌
+	clientInteractive                   // 没有令牌时进行网页授权
+)
+
+type app struct {
+	cfg *config
+	db  *valkeyDB
+	c   *spotify.Client
+	out io.Writer
+}
+
+func setup(g *globalFlags, mode clientMode) (*app, error) {
+	cfg, err := g.load()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := newValkeyDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("连接 Valkey(%s) 失败: %w", cfg.ValkeyAddr, err)
+	}
+
+	a := &app{cfg: cfg, db: db, out: os.Stdout}
+
+	switch mode {
+	case clientInteractive:
+		a.c = spotify.GetClient(db, []byte(cfg.SpotifyKey))
+	case clientRequired:
+		a.c, err = spotify.LoadClient(db, []byte(cfg.SpotifyKey))
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("此命令需要请求 Spotify, 请先运行 spotify-insights login: %w", err)
+		}
+	default:
+		a.c, err = spotify.LoadClient(db, []byte(cfg.SpotifyKey))
+		if err != nil {
+			slog.Debug("无法加载 Spotify 令牌, 以只读模式运行, 数据库中缺少的曲目信息将无法获取", "error", err)
+			a.c = &spotify.Client{Ctx: context.Background()}
+		}
+	}
+
+	return a, nil
+}
+
+func (a *app) close() {
+	err := a.db.Close()
+	if err != nil {
+		slog.Debug("关闭 Valkey 连接失败", "error", err)
+	}
+}
+
+func (a *app) print(v any, t *table) error {
+	return printResult(a.out, a.cfg.Output, v, t)
+}
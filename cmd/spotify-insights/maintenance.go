@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/HenTaku321/spotify-insights-go"
+)
+
+// batchSize 是 check 与 export 每次从数据库读取的播放记录数量
+const batchSize = 1000
+
+func runRebuild(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	err = a.c.RebuildAggregates(a.db)
+	if err != nil {
+		return err
+	}
+
+	total, err := a.c.GetTotalPlaybackHistoryCount(a.db)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TOTAL PLAYS"}}
+	t.add(total)
+
+	return a.print(map[string]int64{"total": total}, t)
+}
+
+func runImport(cmd *command, args []string) error {
+	var source string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		source, args = args[0], args[1:]
+	}
+
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if source == "" && len(paths) > 0 {
+		source, paths = paths[0], paths[1:]
+	}
+
+	if source != "spotify" && source != "lastfm" {
+		return usageErrorf("应指定 spotify 或 lastfm")
+	}
+
+	if len(paths) == 0 {
+		return usageErrorf("应指定至少一个文件")
+	}
+
+	a, err := setup(g, clientRequired)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	var imported int
+
+	if source == "spotify" {
+		imported, err = a.c.ImportExtendedStreamingHistory(a.db, paths...)
+	} else {
+		imported, err = a.c.ImportLastFMScrobbles(a.db, paths...)
+	}
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"IMPORTED"}}
+	t.add(imported)
+
+	return a.print(map[string]int{"imported": imported}, t)
+}
+
+// 检查结果的状态
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type checkReport struct {
+	Total  int64                 `json:"total"`
+	Checks []checkResult         `json:"checks"`
+	Gaps   []spotify.PlaybackGap `json:"gaps"`
+}
+
+var errCheckFailed = errors.New("检查未通过")
+
+// dayIndexes 是一天的播放记录在 playback-history 中的第一条与最后一条的下标
+type dayIndexes struct {
+	first, last int64
+}
+
+func runCheck(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	report := checkReport{Gaps: []spotify.PlaybackGap{}}
+
+	report.Total, err = a.c.GetTotalPlaybackHistoryCount(a.db)
+	if err != nil {
+		return err
+	}
+
+	days := map[string]*dayIndexes{}
+	var firstDay, lastDay, prevPlayedAt string
+	var unordered, invalid int
+
+	for start := int64(0); start < report.Total; start += batchSize {
+		entries, err := a.c.GetPlaybackEntries(a.db, start, start+batchSize-1)
+		if err != nil {
+			return err
+		}
+
+		for i, entry := range entries {
+			index := start + int64(i)
+
+			_, err = time.ParseInLocation(time.DateTime, entry.PlayedAt, time.Local)
+			if err != nil {
+				invalid++
+				continue
+			}
+
+			if entry.PlayedAt < prevPlayedAt {
+				unordered++
+				slog.Debug("播放记录不是按时间顺序排列", "下标", index, "播放时间", entry.PlayedAt, "上一条", prevPlayedAt)
+			}
+			prevPlayedAt = entry.PlayedAt
+
+			day := entry.PlayedAt[:10]
+			if days[day] == nil {
+				days[day] = &dayIndexes{index, index}
+			}
+			days[day].last = index
+
+			if firstDay == "" || day < firstDay {
+				firstDay = day
+			}
+			lastDay = max(lastDay, day)
+		}
+	}
+
+	report.Checks = append(report.Checks, checkResult{"order", checkOK, "播放记录按时间顺序排列"})
+	if unordered > 0 || invalid > 0 {
+		report.Checks[len(report.Checks)-1] = checkResult{"order", checkFail,
+			fmt.Sprintf("%d 条播放记录的时间早于上一条, %d 条的播放时间无效", unordered, invalid)}
+	}
+
+	rangesResult, err := checkDailyRanges(a, days, firstDay, lastDay, report.Total)
+	if err != nil {
+		return err
+	}
+	report.Checks = append(report.Checks, rangesResult)
+
+	gaps, err := a.c.GetGaps(a.db)
+	if err != nil {
+		return err
+	}
+	if gaps != nil {
+		report.Gaps = gaps
+	}
+
+	gapsResult := checkResult{"gaps", checkOK, "没有检测到丢失播放记录的时间段"}
+	if len(gaps) > 0 {
+		gapsResult = checkResult{"gaps", checkWarn, fmt.Sprintf("%d 个时间段的播放记录可能已经丢失, 可从 Spotify 数据导出中导入", len(gaps))}
+	}
+	report.Checks = append(report.Checks, gapsResult)
+
+	reviews, err := a.c.GetLastFMMatchReviews(a.db)
+	if err != nil {
+		return err
+	}
+
+	reviewsResult := checkResult{"lastfm-reviews", checkOK, "没有待确认的 Last.fm 匹配"}
+	if len(reviews) > 0 {
+		reviewsResult = checkResult{"lastfm-reviews", checkWarn, fmt.Sprintf("%d 个 Last.fm 匹配待确认", len(reviews))}
+	}
+	report.Checks = append(report.Checks, reviewsResult)
+
+	t := &table{header: []string{"CHECK", "STATUS", "DETAIL"}}
+	for _, r := range report.Checks {
+		t.add(r.Name, r.Status, r.Detail)
+	}
+
+	err = a.print(report, t)
+	if err != nil {
+		return err
+	}
+
+	// JSON 格式的 gaps 已包含在 report 中
+	if a.cfg.Output == outputTable && len(gaps) > 0 {
+		gapsTable := &table{header: []string{"GAP FROM", "GAP TO", "DETECTED AT"}}
+		for _, gap := range gaps {
+			gapsTable.add(gap.From, gap.To, gap.DetectedAt)
+		}
+
+		fmt.Fprintln(a.out)
+
+		err = a.print(nil, gapsTable)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range report.Checks {
+		if r.Status == checkFail {
+			return errCheckFailed
+		}
+	}
+
+	return nil
+}
+
+// checkDailyRanges 检查每日播放记录的范围是否与播放记录一致, 收听量之和是否等于播放记录总数
+func checkDailyRanges(a *app, days map[string]*dayIndexes, firstDay, lastDay string, total int64) (checkResult, error) {
+	if firstDay == "" {
+		return checkResult{"daily-ranges", checkOK, "没有播放记录"}, nil
+	}
+
+	t1, err := time.ParseInLocation(time.DateOnly, firstDay, time.Local)
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	t2, err := time.ParseInLocation(time.DateOnly, lastDay, time.Local)
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	var mismatched int
+	var sum int64
+
+	for t := t1; !t.After(t2); t = t.AddDate(0, 0, 1) {
+		day := t.Format(time.DateOnly)
+
+		pr, err := a.c.GetPlaybackRangeOnADay(a.db, t)
+		if err != nil {
+			return checkResult{}, err
+		}
+
+		if pr != nil {
+			sum += int64(pr.End - pr.Start + 1)
+		}
+
+		expected := days[day]
+
+		switch {
+		case pr == nil && expected == nil:
+			continue
+		case pr == nil || expected == nil || int64(pr.Start) != expected.first || int64(pr.End) != expected.last:
+			mismatched++
+			slog.Debug("每日播放记录的范围与播放记录不一致", "日期", day, "范围", pr, "播放记录", expected)
+		}
+	}
+
+	if mismatched > 0 || sum != total {
+		return checkResult{"daily-ranges", checkFail,
+			fmt.Sprintf("%d 天的范围与播放记录不一致, 每日收听量之和为 %d, 播放记录共 %d 条, 可运行 rebuild 修复", mismatched, sum, total)}, nil
+	}
+
+	return checkResult{"daily-ranges", checkOK, "每日收听量之和等于播放记录总数"}, nil
+}
+
+func runExport(cmd *command, args []string) error {
+	fs, g := newFlagSet(cmd)
+	format := fs.String("format", "json", "导出格式, json(原始播放记录) 或 csv(带曲目名称)")
+	out := fs.String("out", "", "导出文件路径, 为空时输出到标准输出")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *format != "json" && *format != "csv" {
+		return usageErrorf("-format 应为 json 或 csv")
+	}
+
+	a, err := setup(g, clientOptional)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	w := io.Writer(os.Stdout)
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	total, err := a.c.GetTotalPlaybackHistoryCount(a.db)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		err = exportJSON(a, w, total)
+	} else {
+		err = exportCSV(a, w, total)
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("导出播放记录成功", "数量", total)
+
+	return nil
+}
+
+// exportJSON 以 JSON 数组逐条输出原始播放记录, 不需要一次读取全部
+func exportJSON(a *app, w io.Writer, total int64) error {
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	for start := int64(0); start < total; start += batchSize {
+		entries, err := a.c.GetPlaybackEntries(a.db, start, start+batchSize-1)
+		if err != nil {
+			return err
+		}
+
+		for i, entry := range entries {
+			b, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+
+			sep := ",\n"
+			if start == 0 && i == 0 {
+				sep = "\n"
+			}
+
+			_, err = io.WriteString(w, sep+string(b))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, "\n]\n")
+	return err
+}
+
+// exportCSV 输出带曲目或单集名称的播放记录, 只读模式下数据库中缺少的名称为空
+func exportCSV(a *app, w io.Writer, total int64) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{"played_at", "type", "id", "name", "artists", "album", "ms_played", "source", "context_uri", "device_type", "shuffle"})
+	if err != nil {
+		return err
+	}
+
+	var missing int
+
+	for start := int64(0); start < total; start += batchSize {
+		entries, err := a.c.GetPlaybackEntries(a.db, start, start+batchSize-1)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			typ := entry.Type
+			if typ == "" {
+				typ = spotify.EntryTypeTrack
+			}
+
+			var name, artists, album string
+
+			if typ == spotify.EntryTypeEpisode {
+				episode, err := a.c.GetEpisode(a.db, entry.ID)
+				if err != nil && !errors.Is(err, spotify.ErrNoSpotifyClient) {
+					return err
+				}
+				if episode != nil {
+					name, album = episode.Name, episode.Show.Name
+				}
+			} else {
+				track, err := a.c.GetTrack(a.db, entry.ID)
+				if err != nil && !errors.Is(err, spotify.ErrNoSpotifyClient) {
+					return err
+				}
+				if track != nil {
+					name, artists, album = track.Name, artistNames(track.Artists), track.Album.Name
+				}
+			}
+
+			if name == "" {
+				missing++
+			}
+
+			var contextURI, deviceType, shuffle string
+			if entry.Context != nil {
+				contextURI = entry.Context.URI
+			}
+			if entry.State != nil {
+				deviceType, shuffle = entry.State.DeviceType, strconv.FormatBool(entry.State.Shuffle)
+			}
+
+			msPlayed := ""
+			if entry.MsPlayed > 0 {
+				msPlayed = strconv.Itoa(entry.MsPlayed)
+			}
+
+			err = cw.Write([]string{entry.PlayedAt, typ, entry.ID, name, artists, album, msPlayed, entry.Source, contextURI, deviceType, shuffle})
+			if err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			return err
+		}
+	}
+
+	if missing > 0 {
+		slog.Warn("部分播放记录缺少名称, 可在登录后重新导出", "数量", missing)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// valkeyDB 使用 Valkey(或 Redis) 实现 spotify 包需要的数据库接口, 不存在的键与字段返回空字符串或 0
+type valkeyDB struct {
+	c   *redis.Client
+	ctx context.Context
+}
+
+// redisLogger 将 go-redis 的日志以 Debug 级别输出, 连接失败等错误会由命令返回
+type redisLogger struct{}
+
+func (redisLogger) Printf(ctx context.Context, format string, v ...any) {
+	slog.DebugContext(ctx, fmt.Sprintf(format, v...))
+}
+
+func init() {
+	redis.SetLogger(redisLogger{})
+}
+
+func newValkeyDB(cfg *config) (*valkeyDB, error) {
+	opts := &redis.Options{
+		Addr:     cfg.ValkeyAddr,
+		Password: cfg.ValkeyPassword,
+		DB:       cfg.ValkeyDB,
+	}
+
+	if cfg.ValkeyTLS {
+		host, _, err := net.SplitHostPort(cfg.ValkeyAddr)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = &tls.Config{ServerName: host}
+	}
+
+	db := &valkeyDB{redis.NewClient(opts), context.Background()}
+
+	ctx, cancel := context.WithTimeout(db.ctx, time.Second*10)
+	defer cancel()
+
+	err := db.c.Ping(ctx).Err()
+	if err != nil {
+		_ = db.c.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (db *valkeyDB) Close() error {
+	return db.c.Close()
+}
+
+// ignoreNil 将键或字段不存在的 redis.Nil 视为零值
+func ignoreNil[T any](v T, err error) (T, error) {
+	if errors.Is(err, redis.Nil) {
+		var zero T
+		return zero, nil
+	}
+	return v, err
+}
+
+func (db *valkeyDB) SetString(key string, value string, ex *time.Duration) error {
+	var expiration time.Duration
+	if ex != nil {
+		expiration = *ex
+	}
+	return db.c.Set(db.ctx, key, value, expiration).Err()
+}
+
+func (db *valkeyDB) GetString(key string) (string, error) {
+	return ignoreNil(db.c.Get(db.ctx, key).Result())
+}
+
+func (db *valkeyDB) SetMap(key, field, value string) error {
+	return db.c.HSet(db.ctx, key, field, value).Err()
+}
+
+func (db *valkeyDB) GetMapStr(key, field string) (string, error) {
+	return ignoreNil(db.c.HGet(db.ctx, key, field).Result())
+}
+
+func (db *valkeyDB) GetMapInt64(key, field string) (int64, error) {
+	return ignoreNil(db.c.HGet(db.ctx, key, field).Int64())
+}
+
+func (db *valkeyDB) GetMapLen(key string) (int64, error) {
+	return db.c.HLen(db.ctx, key).Result()
+}
+
+func (db *valkeyDB) GetMapAll(key string) (map[string]string, error) {
+	return db.c.HGetAll(db.ctx, key).Result()
+}
+
+func (db *valkeyDB) CheckIfMapFieldExists(key, field string) (bool, error) {
+	return db.c.HExists(db.ctx, key, field).Result()
+}
+
+func (db *valkeyDB) AppendSlice(key string, value []string) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	values := make([]any, len(value))
+	for i, v := range value {
+		values[i] = v
+	}
+
+	return db.c.RPush(db.ctx, key, values...).Err()
+}
+
+func (db *valkeyDB) GetSlice(key string, start, stop int64) ([]string, error) {
+	return db.c.LRange(db.ctx, key, start, stop).Result()
+}
+
+func (db *valkeyDB) GetSliceByIndex(key string, index int64) (string, error) {
+	return ignoreNil(db.c.LIndex(db.ctx, key, index).Result())
+}
+
+func (db *valkeyDB) GetSliceLen(key string) (int64, error) {
+	return db.c.LLen(db.ctx, key).Result()
+}
+
+func (db *valkeyDB) Delete(key string) error {
+	return db.c.Del(db.ctx, key).Err()
+}
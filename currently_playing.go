@@ -1,9 +1,16 @@
 package spotify
 
-import "time"
+import (
+	"time"
 
+	"github.com/zmb3/spotify/v2"
+)
+
+// CurrentlyPlaying 在播放单集时 Track 为空, Episode 不为空
 type CurrentlyPlaying struct {
 	Track
+	Episode *Episode `json:"episode,omitempty"`
+	Type    string   `json:"type"` // EntryTypeTrack 或 EntryTypeEpisode
 	//InfoStructure InfoStructure `json:"info_structure"`
 	//IsPlaying     bool          `json:"is_playing"`
 	TimeStamp string `json:"timestamp"`
@@ -11,7 +18,11 @@ type CurrentlyPlaying struct {
 
 // FromSpotify
 func (c *Client) GetCurrentlyPlayingTrack(dbc dbClient) (*CurrentlyPlaying, error) {
-	cp, err := c.C.PlayerCurrentlyPlaying(c.Ctx)
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	cp, err := c.C.PlayerCurrentlyPlaying(c.ctx(dbc), spotify.AdditionalTypes(spotify.EpisodeAdditionalType))
 	if err != nil {
 		return nil, err
 	}
@@ -20,10 +31,35 @@ func (c *Client) GetCurrentlyPlayingTrack(dbc dbClient) (*CurrentlyPlaying, erro
 		return nil, nil
 	}
 
-	track, err := c.convertTrack(dbc, cp.Item)
+	timeStamp := time.UnixMilli(int64(cp.Item.Duration)).UTC().Format(time.TimeOnly)
+
+	// 单集会被解析为缺少专辑与艺术家的 FullTrack
+	if cp.Item.Type == EntryTypeEpisode {
+		episode, err := c.getEpisodeCache(dbc, cp.Item.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		return &CurrentlyPlaying{Episode: episode, Type: EntryTypeEpisode, TimeStamp: timeStamp}, nil
+	}
+
+	var track *Track
+
+	if isLocalTrack(&cp.Item.SimpleTrack) {
+		var id string
+
+		id, err = saveLocalTrack(dbc, &cp.Item.SimpleTrack)
+		if err != nil {
+			return nil, err
+		}
+
+		track, err = c.getTrackCache(dbc, id)
+	} else {
+		track, err = c.convertTrack(dbc, cp.Item)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &CurrentlyPlaying{*track, time.UnixMilli(int64(cp.Item.Duration)).UTC().Format(time.TimeOnly)}, nil
+	return &CurrentlyPlaying{Track: *track, Type: EntryTypeTrack, TimeStamp: timeStamp}, nil
 }
@@ -40,6 +40,7 @@ type TrackMap struct {
 	Duration   string   `json:"duration"`
 	Name       string   `json:"name"`
 	Popularity int      `json:"popularity"`
+	ISRC       string   `json:"isrc,omitempty"`
 }
 
 // AlbumMap 是存到数据库列表 spotify-ids 中的存储格式, 与 Album 相比移除了 ID 字段, 且替换 Artists 与 Tracks 字段为 []string, 即 ID
@@ -55,7 +56,24 @@ type AlbumMap struct {
 	//TracksIDs   []string        `json:"tracks_ids"`
 }
 
-// getInfoByID 的 idType 参数应使用 TypeArtist TypeTrack TypeAlbum, 若目标不存在会返回 nil
+// ShowMap 是存到数据库列表 spotify-ids 中的存储格式, 与 Show 相比移除了 ID 字段
+type ShowMap struct {
+	Name      string          `json:"name"`
+	Publisher string          `json:"publisher"`
+	MediaType string          `json:"media_type"`
+	Images    []spotify.Image `json:"images"`
+}
+
+// EpisodeMap 是存到数据库列表 spotify-ids 中的存储格式, 与 Episode 相比移除了 ID 字段, 且替换 Show 字段为 ID
+type EpisodeMap struct {
+	ShowID      string          `json:"show_id"`
+	Duration    string          `json:"duration"`
+	Name        string          `json:"name"`
+	ReleaseDate string          `json:"release_date"`
+	Images      []spotify.Image `json:"images"`
+}
+
+// getInfoByID 的 idType 参数应使用 TypeArtist TypeTrack TypeAlbum TypeShow TypeEpisode, 若目标不存在会返回 nil
 func getInfoByID(dbc dbClient, id string, idType rune) (interface{}, error) {
 	info, err := dbc.GetMapStr("spotify-ids", id)
 	if err != nil {
@@ -73,6 +91,10 @@ func getInfoByID(dbc dbClient, id string, idType rune) (interface{}, error) {
 		i = &ArtistMap{}
 	} else if idType == TypeTrack {
 		i = &TrackMap{}
+	} else if idType == TypeShow {
+		i = &ShowMap{}
+	} else if idType == TypeEpisode {
+		i = &EpisodeMap{}
 	} else {
 		i = &AlbumMap{}
 	}
@@ -91,6 +113,8 @@ func (c *Client) getArtistCache(dbc dbClient, id string) (*Artist, error) {
 		return nil, err
 	}
 
+	observeCache("artist", exists)
+
 	if exists {
 		info, err := getInfoByID(dbc, id, TypeArtist)
 		if err != nil {
@@ -111,7 +135,11 @@ func (c *Client) getArtistCache(dbc dbClient, id string) (*Artist, error) {
 
 	//slog.Debug("数据库中缺少此 ID 信息, 从 Spotify 同步并存储", "ID", id, "类型", "Artist")
 
-	artist, err := c.C.GetArtist(c.Ctx, spotify.ID(id))
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	artist, err := c.C.GetArtist(c.ctx(dbc), spotify.ID(id))
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +162,8 @@ func (c *Client) getAlbumCache(dbc dbClient, id string) (*Album, error) {
 		return nil, err
 	}
 
+	observeCache("album", exists)
+
 	if exists {
 		info, err := getInfoByID(dbc, id, TypeAlbum)
 		if err != nil {
@@ -166,7 +196,11 @@ func (c *Client) getAlbumCache(dbc dbClient, id string) (*Album, error) {
 
 	//slog.Debug("数据库中缺少此 ID 信息, 从 Spotify 同步并存储", "ID", id, "类型", "Album")
 
-	album, err := c.C.GetAlbum(c.Ctx, spotify.ID(id))
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	album, err := c.C.GetAlbum(c.ctx(dbc), spotify.ID(id))
 	if err != nil {
 		return nil, err
 	}
@@ -188,12 +222,24 @@ func (c *Client) getAlbumCache(dbc dbClient, id string) (*Album, error) {
 	return convertedAlbum, nil
 }
 
+// getTrackCache 会将重链接后的 ID 视为原 ID, 本地文件的信息只在播放时存储, 若不存在会返回 nil
 func (c *Client) getTrackCache(dbc dbClient, id string) (*Track, error) {
+	id, err := getCanonicalTrackID(dbc, id)
+	if err != nil {
+		return nil, err
+	}
+
 	exists, err := dbc.CheckIfMapFieldExists("spotify-ids", id)
 	if err != nil {
 		return nil, err
 	}
 
+	observeCache("track", exists)
+
+	if !exists && isLocalID(id) {
+		return nil, nil
+	}
+
 	if exists {
 		info, err := getInfoByID(dbc, id, TypeTrack)
 		if err != nil {
@@ -225,21 +271,41 @@ func (c *Client) getTrackCache(dbc dbClient, id string) (*Track, error) {
 			ID:         id,
 			Name:       m.Name,
 			Popularity: m.Popularity,
+			ISRC:       m.ISRC,
 		}, nil
 	}
 
 	//slog.Debug("数据库中缺少此 ID 信息, 从 Spotify 同步并存储", "ID", id, "类型", "Track")
 
-	track, err := c.C.GetTrack(c.Ctx, spotify.ID(id))
+	// 指定市场后 Spotify 会对当前不可用的曲目进行重链接, 返回的 ID 可能与 id 不同
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	track, err := c.C.GetTrack(c.ctx(dbc), spotify.ID(id), spotify.Market(spotify.MarketFromToken))
 	if err != nil {
 		return nil, err
 	}
 
+	return c.saveFetchedTrack(dbc, id, track)
+}
+
+// saveFetchedTrack 存储以 id 从 Spotify 获取的曲目, 若曲目被重链接会记录重链接并仍以 id 存储
+func (c *Client) saveFetchedTrack(dbc dbClient, id string, track *spotify.FullTrack) (*Track, error) {
 	convertedTrack, err := c.convertTrack(dbc, track)
 	if err != nil {
 		return nil, err
 	}
 
+	if track.ID.String() != id {
+		err = saveLinkedTrackID(dbc, id, track.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		convertedTrack.ID = id
+	}
+
 	convertedTrackM := convertedTrack.toMap()
 
 	err = saveID(dbc, id, convertedTrackM)
@@ -251,3 +317,119 @@ func (c *Client) getTrackCache(dbc dbClient, id string) (*Track, error) {
 
 	return convertedTrack, nil
 }
+
+func (c *Client) getShowCache(dbc dbClient, id string) (*Show, error) {
+	exists, err := dbc.CheckIfMapFieldExists("spotify-ids", id)
+	if err != nil {
+		return nil, err
+	}
+
+	observeCache("show", exists)
+
+	if exists {
+		info, err := getInfoByID(dbc, id, TypeShow)
+		if err != nil {
+			return nil, err
+		}
+
+		m := info.(*ShowMap)
+
+		return &Show{
+			Name:      m.Name,
+			ID:        id,
+			Publisher: m.Publisher,
+			MediaType: m.MediaType,
+			Images:    m.Images,
+		}, nil
+	}
+
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	show, err := c.C.GetShow(c.ctx(dbc), spotify.ID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	convertedShow := c.convertShow(&show.SimpleShow)
+
+	err = saveID(dbc, id, convertedShow.toMap())
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("同步并存储成功", "名称", convertedShow.Name, "ID", id, "类型", "Show")
+
+	return convertedShow, nil
+}
+
+func (c *Client) getEpisodeCache(dbc dbClient, id string) (*Episode, error) {
+	exists, err := dbc.CheckIfMapFieldExists("spotify-ids", id)
+	if err != nil {
+		return nil, err
+	}
+
+	observeCache("episode", exists)
+
+	if exists {
+		info, err := getInfoByID(dbc, id, TypeEpisode)
+		if err != nil {
+			return nil, err
+		}
+
+		m := info.(*EpisodeMap)
+
+		show, err := c.getShowCache(dbc, m.ShowID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Episode{
+			Show:        *show,
+			Duration:    m.Duration,
+			ID:          id,
+			Name:        m.Name,
+			ReleaseDate: m.ReleaseDate,
+			Images:      m.Images,
+		}, nil
+	}
+
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	episode, err := c.C.GetEpisode(c.ctx(dbc), id)
+	if err != nil {
+		return nil, err
+	}
+
+	convertedEpisode, err := c.convertEpisode(dbc, episode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveID(dbc, id, convertedEpisode.toMap())
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("同步并存储成功", "名称", convertedEpisode.Name, "ID", id, "类型", "Episode")
+
+	return convertedEpisode, nil
+}
+
+// GetTrack 返回曲目信息, 数据库中没有时从 Spotify 获取并存储, 本地文件的信息不存在时返回 nil
+func (c *Client) GetTrack(dbc dbClient, id string) (*Track, error) {
+	return c.getTrackCache(dbc, id)
+}
+
+// GetArtist 返回艺术家信息, 数据库中没有时从 Spotify 获取并存储
+func (c *Client) GetArtist(dbc dbClient, id string) (*Artist, error) {
+	return c.getArtistCache(dbc, id)
+}
+
+// GetEpisode 返回单集信息, 数据库中没有时从 Spotify 获取并存储
+func (c *Client) GetEpisode(dbc dbClient, id string) (*Episode, error) {
+	return c.getEpisodeCache(dbc, id)
+}
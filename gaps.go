@@ -0,0 +1,59 @@
+package spotify
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// PlaybackGap 是数据库列表 playback-gaps 中的存储格式, 表示 From 与 To 之间(不包括两端)的播放记录可能已经丢失
+// 最近播放最多只能获取 50 条, 收集程序停止运行过久时会出现
+type PlaybackGap struct {
+	From       string `json:"from"` // 已存储的最后一次来自最近播放的曲目的播放时间
+	To         string `json:"to"`   // 此次获取到的最早一次播放的时间
+	DetectedAt string `json:"detected_at"`
+}
+
+func (c *Client) savePlaybackGap(dbc dbClient, from, to string) error {
+	gap := &PlaybackGap{From: from, To: to, DetectedAt: time.Now().Format(time.DateTime)}
+
+	j, err := json.Marshal(gap)
+	if err != nil {
+		return err
+	}
+
+	err = dbc.AppendSlice("playback-gaps", []string{string(j)})
+	if err != nil {
+		return err
+	}
+
+	// 以 Error 级别记录, 以免被忽略
+	slog.Error("最近播放中找不到已存储的最后一次播放, 此时间段内的部分播放记录已经丢失, 请之后从 Spotify 数据导出中导入(ImportExtendedStreamingHistory)", "从", from, "到", to)
+
+	c.emitWebhook(dbc, WebhookGapDetected, gap)
+
+	return nil
+}
+
+// GetGaps 返回所有检测到的可能丢失播放记录的时间段, 按检测时间排序
+func (c *Client) GetGaps(dbc dbClient) ([]PlaybackGap, error) {
+	gapsSli, err := dbc.GetSlice("playback-gaps", 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps []PlaybackGap
+
+	for _, s := range gapsSli {
+		gap := PlaybackGap{}
+
+		err = json.Unmarshal([]byte(s), &gap)
+		if err != nil {
+			return nil, err
+		}
+
+		gaps = append(gaps, gap)
+	}
+
+	return gaps, nil
+}
@@ -0,0 +1,560 @@
+package spotify
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/zmb3/spotify/v2"
+)
+
+//go:embed graphql/schema.graphql
+var graphQLSchema string
+
+const (
+	graphQLMaxParallelism = 50 // 同一层最多并行解析的字段数, 也是一批最多能合并的 ID 数
+	graphQLMaxDepth       = 10
+	graphQLMaxBodySize    = 1 << 20
+)
+
+// graphQLRequest 是一次 GraphQL 请求的状态, 通过 context 传递给解析器, 加载器的结果只在请求内缓存
+type graphQLRequest struct {
+	c       *Client
+	dbc     dbClient
+	tracks  *batchLoader[*trackNode]
+	albums  *batchLoader[*AlbumMap]
+	artists *batchLoader[*ArtistMap]
+}
+
+type graphQLRequestKey struct{}
+
+func (c *Client) newGraphQLRequest(dbc dbClient) *graphQLRequest {
+	return &graphQLRequest{
+		c:   c,
+		dbc: dbc,
+		tracks: newBatchLoader(graphQLMaxParallelism, func(ids []string) ([]*trackNode, []error) {
+			return c.fetchTracks(dbc, ids)
+		}),
+		albums: newBatchLoader(graphQLMaxParallelism, func(ids []string) ([]*AlbumMap, []error) {
+			return c.fetchAlbums(dbc, ids)
+		}),
+		artists: newBatchLoader(graphQLMaxParallelism, func(ids []string) ([]*ArtistMap, []error) {
+			return c.fetchArtists(dbc, ids)
+		}),
+	}
+}
+
+func graphQLRequestFrom(ctx context.Context) *graphQLRequest {
+	return ctx.Value(graphQLRequestKey{}).(*graphQLRequest)
+}
+
+type graphQLParams struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQLHandler 返回提供 GraphQL 接口的 http.Handler, schema 见 graphql/schema.graphql
+// GET 请求使用查询参数 query operationName variables(JSON), POST 请求的请求体为相同字段的 JSON
+// 同一请求中并行解析的曲目 专辑 艺术家会合并读取, 数据库中缺少的信息合并为尽量少的 Spotify 请求
+func (c *Client) GraphQLHandler(dbc dbClient) http.Handler {
+	schema := graphql.MustParseSchema(graphQLSchema, &graphQLQuery{},
+		graphql.MaxParallelism(graphQLMaxParallelism),
+		graphql.MaxDepth(graphQLMaxDepth),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params graphQLParams
+
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			params.Query = q.Get("query")
+			params.OperationName = q.Get("operationName")
+
+			if v := q.Get("variables"); v != "" {
+				err := json.Unmarshal([]byte(v), &params.Variables)
+				if err != nil {
+					writeAPIError(w, invalidParameter("variables", "应为 JSON 对象"))
+					return
+				}
+			}
+		case http.MethodPost:
+			err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxBodySize)).Decode(&params)
+			if err != nil {
+				writeAPIError(w, &apiError{http.StatusBadRequest, APIErrorInvalidParameter, "请求体应为 JSON 对象"})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeAPIError(w, &apiError{http.StatusMethodNotAllowed, APIErrorMethodNotAllowed, "只接受 GET 与 POST 请求"})
+			return
+		}
+
+		if params.Query == "" {
+			writeAPIError(w, invalidParameter("query", "不能为空"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), graphQLRequestKey{}, c.newGraphQLRequest(dbc))
+
+		resp := schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+		// 与 writeAPIError 相同, 只有参数错误与只读模式的错误会返回原本的信息
+		for _, e := range resp.Errors {
+			var ae *apiError
+			if e.ResolverError == nil || errors.As(e.ResolverError, &ae) || errors.Is(e.ResolverError, ErrNoSpotifyClient) {
+				continue
+			}
+
+			slog.Warn("GraphQL 请求处理失败", "error", e.ResolverError, "path", e.Path)
+			e.Message = "服务器内部错误"
+		}
+
+		b, err := json.Marshal(resp)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		_, err = w.Write(b)
+		if err != nil {
+			slog.Debug("写入响应失败", "error", err)
+		}
+	})
+}
+
+// graphQLEntity 将 ChartEntity 枚举转换为 ChartEntityTracks 等
+func graphQLEntity(entity string) string {
+	return strings.ToLower(entity)
+}
+
+// graphQLLimit 检查 limit 参数, 默认值见 schema
+func graphQLLimit(limit int32, max int) (int, error) {
+	if limit < 1 || int(limit) > max {
+		return 0, invalidParameter("limit", "应在 1 与 "+strconv.Itoa(max)+" 之间")
+	}
+
+	return int(limit), nil
+}
+
+func loadGraphQLTrack(ctx context.Context, id string) (*graphQLTrack, error) {
+	node, err := graphQLRequestFrom(ctx).tracks.load(id)
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	return &graphQLTrack{node}, nil
+}
+
+func loadGraphQLAlbum(ctx context.Context, id string) (*graphQLAlbum, error) {
+	m, err := graphQLRequestFrom(ctx).albums.load(id)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	return &graphQLAlbum{id, m}, nil
+}
+
+func loadGraphQLArtist(ctx context.Context, id string) (*graphQLArtist, error) {
+	m, err := graphQLRequestFrom(ctx).artists.load(id)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	return &graphQLArtist{id, m}, nil
+}
+
+// loadGraphQLArtists 将 ids 合并为一批加载, 信息不存在的艺术家会被跳过
+func loadGraphQLArtists(ctx context.Context, ids []string) ([]*graphQLArtist, error) {
+	maps, err := graphQLRequestFrom(ctx).artists.loadMany(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	artists := []*graphQLArtist{}
+
+	for i, m := range maps {
+		if m != nil {
+			artists = append(artists, &graphQLArtist{ids[i], m})
+		}
+	}
+
+	return artists, nil
+}
+
+func newGraphQLImages(images []spotify.Image) []*graphQLImage {
+	res := []*graphQLImage{}
+	for _, image := range images {
+		res = append(res, &graphQLImage{image})
+	}
+	return res
+}
+
+// optionalInt32 将 0 视为 null
+func optionalInt32(n int) *int32 {
+	if n == 0 {
+		return nil
+	}
+
+	v := int32(n)
+	return &v
+}
+
+type graphQLQuery struct{}
+
+func (*graphQLQuery) History(ctx context.Context, args struct {
+	Offset int32
+	Limit  int32
+	Order  string
+}) (*graphQLHistoryPage, error) {
+	req := graphQLRequestFrom(ctx)
+
+	offset := int64(args.Offset)
+	if offset < 0 {
+		return nil, invalidParameter("offset", "不能小于 0")
+	}
+
+	limit, err := graphQLLimit(args.Limit, apiMaxHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := req.c.GetTotalPlaybackHistoryCount(req.dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &graphQLHistoryPage{total: total, offset: offset, limit: int64(limit), items: []*graphQLPlay{}}
+
+	if offset >= total {
+		return page, nil
+	}
+
+	start, stop := historyWindow(total, offset, int64(limit), args.Order == "DESC")
+
+	entries, err := getPlaybackEntries(req.dbc, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		page.items = append(page.items, &graphQLPlay{start + int64(i), entry})
+	}
+
+	if args.Order == "DESC" {
+		slices.Reverse(page.items)
+	}
+
+	return page, nil
+}
+
+func (*graphQLQuery) Track(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLTrack, error) {
+	return loadGraphQLTrack(ctx, string(args.ID))
+}
+
+func (*graphQLQuery) Album(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLAlbum, error) {
+	return loadGraphQLAlbum(ctx, string(args.ID))
+}
+
+func (*graphQLQuery) Artist(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLArtist, error) {
+	return loadGraphQLArtist(ctx, string(args.ID))
+}
+
+func (*graphQLQuery) Tops(ctx context.Context, args struct {
+	Entity string
+	From   string
+	To     string
+	Limit  int32
+}) ([]*graphQLTopItem, error) {
+	req := graphQLRequestFrom(ctx)
+
+	t1, t2, err := parseDateRange(args.From, args.To)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := graphQLLimit(args.Limit, apiMaxTopsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	charts, err := req.c.computeCharts(req.dbc, t1, t2, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGraphQLTopItems(graphQLEntity(args.Entity), charts[graphQLEntity(args.Entity)]), nil
+}
+
+func (*graphQLQuery) Chart(ctx context.Context, args struct {
+	Period string
+	Entity string
+	Date   string
+}) (*[]*graphQLTopItem, error) {
+	req := graphQLRequestFrom(ctx)
+
+	date, err := parseDate("date", args.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := graphQLEntity(args.Entity)
+
+	tops, err := req.c.GetChart(req.dbc, strings.ToLower(args.Period), entity, date)
+	if err != nil || tops == nil {
+		return nil, err
+	}
+
+	items := newGraphQLTopItems(entity, tops)
+	return &items, nil
+}
+
+func (*graphQLQuery) ChartDates(ctx context.Context, args struct {
+	Period string
+	Entity string
+}) ([]string, error) {
+	req := graphQLRequestFrom(ctx)
+
+	dates, err := req.c.GetChartDates(req.dbc, strings.ToLower(args.Period), graphQLEntity(args.Entity))
+	if err != nil {
+		return nil, err
+	}
+
+	if dates == nil {
+		dates = []string{}
+	}
+
+	return dates, nil
+}
+
+func (*graphQLQuery) Hourly(ctx context.Context) ([]*graphQLHourlyCount, error) {
+	req := graphQLRequestFrom(ctx)
+
+	counts, err := req.c.GetHourlyPlayBackCounts(req.dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*graphQLHourlyCount
+
+	for hour := 0; hour < 24; hour++ {
+		res = append(res, &graphQLHourlyCount{int32(hour), int32(counts[hour])})
+	}
+
+	return res, nil
+}
+
+type graphQLHistoryPage struct {
+	total  int64
+	offset int64
+	limit  int64
+	items  []*graphQLPlay
+}
+
+func (p *graphQLHistoryPage) Total() int32          { return int32(p.total) }
+func (p *graphQLHistoryPage) Offset() int32         { return int32(p.offset) }
+func (p *graphQLHistoryPage) Limit() int32          { return int32(p.limit) }
+func (p *graphQLHistoryPage) Items() []*graphQLPlay { return p.items }
+
+type graphQLPlay struct {
+	index int64
+	entry PlaybackEntry
+}
+
+func (p *graphQLPlay) Index() int32     { return int32(p.index) }
+func (p *graphQLPlay) PlayedAt() string { return p.entry.PlayedAt }
+func (p *graphQLPlay) MsPlayed() *int32 { return optionalInt32(p.entry.MsPlayed) }
+
+func (p *graphQLPlay) Type() string {
+	if isEpisode(p.entry) {
+		return "EPISODE"
+	}
+	return "TRACK"
+}
+
+func (p *graphQLPlay) Context() *graphQLPlaybackContext {
+	if p.entry.Context == nil {
+		return nil
+	}
+	return &graphQLPlaybackContext{p.entry.Context}
+}
+
+func (p *graphQLPlay) Track(ctx context.Context) (*graphQLTrack, error) {
+	if isEpisode(p.entry) {
+		return nil, nil
+	}
+	return loadGraphQLTrack(ctx, p.entry.ID)
+}
+
+func (p *graphQLPlay) Episode(ctx context.Context) (*graphQLEpisode, error) {
+	if !isEpisode(p.entry) {
+		return nil, nil
+	}
+
+	req := graphQLRequestFrom(ctx)
+
+	episode, err := req.c.getEpisodeCache(req.dbc, p.entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &graphQLEpisode{episode}, nil
+}
+
+type graphQLPlaybackContext struct {
+	pc *PlaybackContext
+}
+
+func (pc *graphQLPlaybackContext) Type() string { return getContextType(pc.pc) }
+func (pc *graphQLPlaybackContext) URI() string  { return pc.pc.URI }
+
+type graphQLTrack struct {
+	node *trackNode
+}
+
+func (t *graphQLTrack) ID() graphql.ID    { return graphql.ID(t.node.ID) }
+func (t *graphQLTrack) Name() string      { return t.node.Name }
+func (t *graphQLTrack) Duration() string  { return t.node.Duration }
+func (t *graphQLTrack) Popularity() int32 { return int32(t.node.Popularity) }
+
+func (t *graphQLTrack) ISRC() *string {
+	if t.node.ISRC == "" {
+		return nil
+	}
+	return &t.node.ISRC
+}
+
+func (t *graphQLTrack) Album(ctx context.Context) (*graphQLAlbum, error) {
+	if t.node.AlbumID == "" {
+		return nil, nil
+	}
+	return loadGraphQLAlbum(ctx, t.node.AlbumID)
+}
+
+func (t *graphQLTrack) Artists(ctx context.Context) ([]*graphQLArtist, error) {
+	return loadGraphQLArtists(ctx, t.node.ArtistsIDs)
+}
+
+func (t *graphQLTrack) PlayCount(ctx context.Context) (int32, error) {
+	req := graphQLRequestFrom(ctx)
+
+	count, err := req.c.GetTrackPlaybackCount(req.dbc, t.node.ID)
+	return int32(count), err
+}
+
+type graphQLAlbum struct {
+	id string
+	m  *AlbumMap
+}
+
+func (a *graphQLAlbum) ID() graphql.ID          { return graphql.ID(a.id) }
+func (a *graphQLAlbum) Name() string            { return a.m.Name }
+func (a *graphQLAlbum) ReleaseDate() string     { return a.m.ReleaseDate }
+func (a *graphQLAlbum) TotalTracks() int32      { return int32(a.m.TotalTracks) }
+func (a *graphQLAlbum) Popularity() int32       { return int32(a.m.Popularity) }
+func (a *graphQLAlbum) Images() []*graphQLImage { return newGraphQLImages(a.m.Images) }
+
+func (a *graphQLAlbum) Artists(ctx context.Context) ([]*graphQLArtist, error) {
+	return loadGraphQLArtists(ctx, a.m.ArtistsIDs)
+}
+
+type graphQLArtist struct {
+	id string
+	m  *ArtistMap
+}
+
+func (a *graphQLArtist) ID() graphql.ID          { return graphql.ID(a.id) }
+func (a *graphQLArtist) Name() string            { return a.m.Name }
+func (a *graphQLArtist) Popularity() int32       { return int32(a.m.Popularity) }
+func (a *graphQLArtist) Followers() int32        { return int32(a.m.Followers) }
+func (a *graphQLArtist) Images() []*graphQLImage { return newGraphQLImages(a.m.Images) }
+
+func (a *graphQLArtist) Genres() []string {
+	if a.m.Genres == nil {
+		return []string{}
+	}
+	return a.m.Genres
+}
+
+type graphQLImage struct {
+	image spotify.Image
+}
+
+func (i *graphQLImage) URL() string    { return i.image.URL }
+func (i *graphQLImage) Width() *int32  { return optionalInt32(int(i.image.Width)) }
+func (i *graphQLImage) Height() *int32 { return optionalInt32(int(i.image.Height)) }
+
+type graphQLEpisode struct {
+	e *Episode
+}
+
+func (e *graphQLEpisode) ID() graphql.ID          { return graphql.ID(e.e.ID) }
+func (e *graphQLEpisode) Name() string            { return e.e.Name }
+func (e *graphQLEpisode) Duration() string        { return e.e.Duration }
+func (e *graphQLEpisode) ReleaseDate() string     { return e.e.ReleaseDate }
+func (e *graphQLEpisode) Images() []*graphQLImage { return newGraphQLImages(e.e.Images) }
+func (e *graphQLEpisode) Show() *graphQLShow      { return &graphQLShow{&e.e.Show} }
+
+type graphQLShow struct {
+	s *Show
+}
+
+func (s *graphQLShow) ID() graphql.ID          { return graphql.ID(s.s.ID) }
+func (s *graphQLShow) Name() string            { return s.s.Name }
+func (s *graphQLShow) Publisher() string       { return s.s.Publisher }
+func (s *graphQLShow) Images() []*graphQLImage { return newGraphQLImages(s.s.Images) }
+
+type graphQLTopItem struct {
+	entity string
+	top    Tops
+}
+
+func newGraphQLTopItems(entity string, tops []Tops) []*graphQLTopItem {
+	items := []*graphQLTopItem{}
+	for _, top := range tops {
+		items = append(items, &graphQLTopItem{entity, top})
+	}
+	return items
+}
+
+func (t *graphQLTopItem) ID() graphql.ID { return graphql.ID(t.top.ID) }
+func (t *graphQLTopItem) Count() int32   { return int32(t.top.Count) }
+
+func (t *graphQLTopItem) Track(ctx context.Context) (*graphQLTrack, error) {
+	if t.entity != ChartEntityTracks {
+		return nil, nil
+	}
+	return loadGraphQLTrack(ctx, t.top.ID)
+}
+
+func (t *graphQLTopItem) Artist(ctx context.Context) (*graphQLArtist, error) {
+	if t.entity != ChartEntityArtists {
+		return nil, nil
+	}
+	return loadGraphQLArtist(ctx, t.top.ID)
+}
+
+func (t *graphQLTopItem) Album(ctx context.Context) (*graphQLAlbum, error) {
+	if t.entity != ChartEntityAlbums {
+		return nil, nil
+	}
+	return loadGraphQLAlbum(ctx, t.top.ID)
+}
+
+type graphQLHourlyCount struct {
+	hour  int32
+	count int32
+}
+
+func (h *graphQLHourlyCount) Hour() int32  { return h.hour }
+func (h *graphQLHourlyCount) Count() int32 { return h.count }
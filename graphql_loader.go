@@ -0,0 +1,374 @@
+package spotify
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// graphQLBatchWait 是批量加载收集 ID 的等待时间, 同一层并行解析的字段会在此期间加入同一批
+const graphQLBatchWait = time.Millisecond * 2
+
+// Spotify 批量获取接口每次请求的最大 ID 数
+const (
+	spotifyMaxTracksPerRequest  = 50
+	spotifyMaxArtistsPerRequest = 50
+	spotifyMaxAlbumsPerRequest  = 20
+)
+
+// batchLoader 将同一请求中并行解析的字段需要的 ID 合并为一次 fetch, 结果在请求内缓存
+// fetch 返回的两个切片与 ids 一一对应, 信息不存在时值为 nil, 整批失败时值可以为 nil
+type batchLoader[T any] struct {
+	fetch    func(ids []string) ([]T, []error)
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[string]*loaderResult[T]
+	batch *loaderBatch[T]
+}
+
+type loaderResult[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+type loaderBatch[T any] struct {
+	ids     []string
+	results []*loaderResult[T]
+	full    chan struct{}
+}
+
+func newBatchLoader[T any](maxBatch int, fetch func(ids []string) ([]T, []error)) *batchLoader[T] {
+	return &batchLoader[T]{fetch: fetch, maxBatch: maxBatch, cache: map[string]*loaderResult[T]{}}
+}
+
+func (l *batchLoader[T]) load(id string) (T, error) {
+	l.mu.Lock()
+	r := l.enqueue(id)
+	l.mu.Unlock()
+
+	<-r.done
+	return r.value, r.err
+}
+
+// loadMany 将 ids 加入同一批后等待全部结果, 遇到错误时返回第一个错误
+func (l *batchLoader[T]) loadMany(ids []string) ([]T, error) {
+	l.mu.Lock()
+	results := make([]*loaderResult[T], len(ids))
+	for i, id := range ids {
+		results[i] = l.enqueue(id)
+	}
+	l.mu.Unlock()
+
+	values := make([]T, len(ids))
+
+	for i, r := range results {
+		<-r.done
+		if r.err != nil {
+			return nil, r.err
+		}
+		values[i] = r.value
+	}
+
+	return values, nil
+}
+
+// enqueue 返回 id 的结果, 未缓存时加入当前批, 调用时需持有 l.mu
+func (l *batchLoader[T]) enqueue(id string) *loaderResult[T] {
+	if r, ok := l.cache[id]; ok {
+		return r
+	}
+
+	r := &loaderResult[T]{done: make(chan struct{})}
+	l.cache[id] = r
+
+	if l.batch == nil {
+		l.batch = &loaderBatch[T]{full: make(chan struct{})}
+		go l.dispatch(l.batch)
+	}
+
+	b := l.batch
+	b.ids = append(b.ids, id)
+	b.results = append(b.results, r)
+
+	if len(b.ids) >= l.maxBatch {
+		l.batch = nil
+		close(b.full)
+	}
+
+	return r
+}
+
+func (l *batchLoader[T]) dispatch(b *loaderBatch[T]) {
+	timer := time.NewTimer(graphQLBatchWait)
+
+	select {
+	case <-timer.C:
+	case <-b.full:
+		timer.Stop()
+	}
+
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	values, errs := l.fetch(b.ids)
+
+	for i, r := range b.results {
+		if values != nil {
+			r.value = values[i]
+		}
+		r.err = errs[i]
+		close(r.done)
+	}
+}
+
+// batchError 返回 n 个相同的错误, 用于整批失败
+func batchError(n int, err error) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// loadCachedInfos 从数据库读取 ids 的信息, 返回值与 ids 一一对应, 同时返回缺少信息且不是本地文件的 ID 的下标
+func loadCachedInfos[T any](dbc dbClient, ids []string, idType rune, typ string) ([]*T, []int, error) {
+	values := make([]*T, len(ids))
+
+	var missing []int
+
+	for i, id := range ids {
+		info, err := getInfoByID(dbc, id, idType)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		observeCache(typ, info != nil)
+
+		if info != nil {
+			values[i] = info.(*T)
+		} else if !isLocalID(id) {
+			missing = append(missing, i)
+		}
+	}
+
+	return values, missing, nil
+}
+
+// setErrors 将 errs 中 indexes 对应的错误设为 err
+func setErrors(errs []error, indexes []int, err error) {
+	for _, i := range indexes {
+		errs[i] = err
+	}
+}
+
+func spotifyIDsAt(ids []string, indexes []int) []spotify.ID {
+	res := make([]spotify.ID, len(indexes))
+	for i, index := range indexes {
+		res[i] = spotify.ID(ids[index])
+	}
+	return res
+}
+
+// trackNode 是 GraphQL 中曲目的数据, ID 为重链接前的 ID
+type trackNode struct {
+	ID string
+	*TrackMap
+}
+
+// fetchTracks 与 getTrackCache 相同, 但数据库中缺少的曲目会合并为尽量少的 Spotify 请求
+func (c *Client) fetchTracks(dbc dbClient, ids []string) ([]*trackNode, []error) {
+	canonicalIDs := make([]string, len(ids))
+
+	for i, id := range ids {
+		canonicalID, err := getCanonicalTrackID(dbc, id)
+		if err != nil {
+			return nil, batchError(len(ids), err)
+		}
+
+		canonicalIDs[i] = canonicalID
+	}
+
+	maps, missing, err := loadCachedInfos[TrackMap](dbc, canonicalIDs, TypeTrack, "track")
+	if err != nil {
+		return nil, batchError(len(ids), err)
+	}
+
+	errs := make([]error, len(ids))
+
+	for chunk := range slices.Chunk(missing, spotifyMaxTracksPerRequest) {
+		if c.C == nil {
+			setErrors(errs, chunk, ErrNoSpotifyClient)
+			continue
+		}
+
+		tracks, err := c.C.GetTracks(c.ctx(dbc), spotifyIDsAt(canonicalIDs, chunk), spotify.Market(spotify.MarketFromToken))
+		if err != nil {
+			setErrors(errs, chunk, err)
+			continue
+		}
+
+		var albumIDs, artistIDs []string
+
+		for _, track := range tracks {
+			if track == nil {
+				continue
+			}
+
+			albumIDs = append(albumIDs, track.Album.ID.String())
+
+			for _, artist := range slices.Concat(track.Artists, track.Album.Artists) {
+				artistIDs = append(artistIDs, artist.ID.String())
+			}
+		}
+
+		c.prefetchAlbumsAndArtists(dbc, albumIDs, artistIDs)
+
+		for j, i := range chunk {
+			if j >= len(tracks) || tracks[j] == nil {
+				continue
+			}
+
+			track, err := c.saveFetchedTrack(dbc, canonicalIDs[i], tracks[j])
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			maps[i] = track.toMap()
+		}
+	}
+
+	nodes := make([]*trackNode, len(ids))
+
+	for i, m := range maps {
+		if m != nil {
+			nodes[i] = &trackNode{canonicalIDs[i], m}
+		}
+	}
+
+	return nodes, errs
+}
+
+// prefetchAlbumsAndArtists 在转换从 Spotify 获取的曲目或专辑之前批量获取其中的专辑与艺术家, 避免 convertTrack 与 convertAlbum 逐个请求
+// 失败时不返回错误, 之后的转换会逐个重试并返回错误
+func (c *Client) prefetchAlbumsAndArtists(dbc dbClient, albumIDs, artistIDs []string) {
+	slices.Sort(albumIDs)
+	slices.Sort(artistIDs)
+
+	if len(albumIDs) > 0 {
+		c.fetchAlbums(dbc, slices.Compact(albumIDs))
+	}
+
+	if len(artistIDs) > 0 {
+		c.fetchArtists(dbc, slices.Compact(artistIDs))
+	}
+}
+
+// fetchAlbums 与 getAlbumCache 相同, 但数据库中缺少的专辑会合并为尽量少的 Spotify 请求
+func (c *Client) fetchAlbums(dbc dbClient, ids []string) ([]*AlbumMap, []error) {
+	maps, missing, err := loadCachedInfos[AlbumMap](dbc, ids, TypeAlbum, "album")
+	if err != nil {
+		return nil, batchError(len(ids), err)
+	}
+
+	errs := make([]error, len(ids))
+
+	for chunk := range slices.Chunk(missing, spotifyMaxAlbumsPerRequest) {
+		if c.C == nil {
+			setErrors(errs, chunk, ErrNoSpotifyClient)
+			continue
+		}
+
+		albums, err := c.C.GetAlbums(c.ctx(dbc), spotifyIDsAt(ids, chunk))
+		if err != nil {
+			setErrors(errs, chunk, err)
+			continue
+		}
+
+		var artistIDs []string
+
+		for _, album := range albums {
+			if album == nil {
+				continue
+			}
+
+			for _, artist := range album.Artists {
+				artistIDs = append(artistIDs, artist.ID.String())
+			}
+		}
+
+		c.prefetchAlbumsAndArtists(dbc, nil, artistIDs)
+
+		for j, i := range chunk {
+			if j >= len(albums) || albums[j] == nil {
+				continue
+			}
+
+			album, err := c.convertAlbum(dbc, albums[j])
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			m := album.toMap()
+
+			err = saveID(dbc, ids[i], m)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			maps[i] = m
+		}
+	}
+
+	return maps, errs
+}
+
+// fetchArtists 与 getArtistCache 相同, 但数据库中缺少的艺术家会合并为尽量少的 Spotify 请求
+func (c *Client) fetchArtists(dbc dbClient, ids []string) ([]*ArtistMap, []error) {
+	maps, missing, err := loadCachedInfos[ArtistMap](dbc, ids, TypeArtist, "artist")
+	if err != nil {
+		return nil, batchError(len(ids), err)
+	}
+
+	errs := make([]error, len(ids))
+
+	for chunk := range slices.Chunk(missing, spotifyMaxArtistsPerRequest) {
+		if c.C == nil {
+			setErrors(errs, chunk, ErrNoSpotifyClient)
+			continue
+		}
+
+		artists, err := c.C.GetArtists(c.ctx(dbc), spotifyIDsAt(ids, chunk)...)
+		if err != nil {
+			setErrors(errs, chunk, err)
+			continue
+		}
+
+		for j, i := range chunk {
+			if j >= len(artists) || artists[j] == nil {
+				continue
+			}
+
+			m := c.convertArtist(artists[j]).toMap()
+
+			err = saveID(dbc, ids[i], m)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			maps[i] = m
+		}
+	}
+
+	return maps, errs
+}
@@ -0,0 +1,375 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/HenTaku321/spotify-insights-go/insightspb"
+	"github.com/zmb3/spotify/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcHistoryPageSize 是 StreamHistory 每次从数据库读取的播放记录数
+const grpcHistoryPageSize = 500
+
+type grpcService struct {
+	insightspb.UnimplementedInsightsServiceServer
+
+	c   *Client
+	dbc dbClient
+}
+
+// GRPCService 返回 insightspb.InsightsService 的实现, 可通过 insightspb.RegisterInsightsServiceServer 注册到已有的 grpc.Server
+// WatchPlays 需要同时运行 Run, Client.C 为 nil 时 GetNowPlaying 返回 FAILED_PRECONDITION
+func (c *Client) GRPCService(dbc dbClient) insightspb.InsightsServiceServer {
+	return &grpcService{c: c, dbc: dbc}
+}
+
+// grpcError 将错误转换为 gRPC 状态, 与 writeAPIError 相同, 只有参数错误与只读模式的错误会返回原本的信息
+func grpcError(err error) error {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		return status.Error(codes.InvalidArgument, ae.message)
+	}
+
+	if errors.Is(err, ErrNoSpotifyClient) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	slog.Warn("gRPC 请求处理失败", "error", err)
+	return status.Error(codes.Internal, "服务器内部错误")
+}
+
+func (s *grpcService) StreamHistory(req *insightspb.StreamHistoryRequest, stream grpc.ServerStreamingServer[insightspb.Play]) error {
+	if req.Offset < 0 {
+		return grpcError(invalidParameter("offset", "不能小于 0"))
+	}
+
+	if req.Limit < 0 {
+		return grpcError(invalidParameter("limit", "不能小于 0"))
+	}
+
+	total, err := s.c.GetTotalPlaybackHistoryCount(s.dbc)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	if req.Offset >= total {
+		return nil
+	}
+
+	limit := total - req.Offset
+	if req.Limit > 0 {
+		limit = min(req.Limit, limit)
+	}
+
+	desc := req.Order == insightspb.Order_ORDER_DESC
+
+	for sent := int64(0); sent < limit; sent += grpcHistoryPageSize {
+		start, stop := historyWindow(total, req.Offset+sent, min(grpcHistoryPageSize, limit-sent), desc)
+
+		entries, err := getPlaybackEntries(s.dbc, start, stop)
+		if err != nil {
+			return grpcError(err)
+		}
+
+		plays := make([]*insightspb.Play, 0, len(entries))
+
+		for i, entry := range entries {
+			play, err := s.c.grpcPlay(s.dbc, start+int64(i), entry)
+			if err != nil {
+				return grpcError(err)
+			}
+
+			plays = append(plays, play)
+		}
+
+		if desc {
+			slices.Reverse(plays)
+		}
+
+		for _, play := range plays {
+			err = stream.Send(play)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *grpcService) GetTops(ctx context.Context, req *insightspb.GetTopsRequest) (*insightspb.GetTopsResponse, error) {
+	var entity string
+
+	switch req.Entity {
+	case insightspb.ChartEntity_CHART_ENTITY_UNSPECIFIED, insightspb.ChartEntity_CHART_ENTITY_TRACKS:
+		entity = ChartEntityTracks
+	case insightspb.ChartEntity_CHART_ENTITY_ARTISTS:
+		entity = ChartEntityArtists
+	case insightspb.ChartEntity_CHART_ENTITY_ALBUMS:
+		entity = ChartEntityAlbums
+	default:
+		return nil, grpcError(invalidParameter("entity", "无效的类型"))
+	}
+
+	t1, t2, err := parseDateRange(req.From, req.To)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = apiDefaultLimit
+	}
+
+	if limit < 1 || limit > apiMaxTopsLimit {
+		return nil, grpcError(invalidParameter("limit", "应在 1 与 "+strconv.Itoa(apiMaxTopsLimit)+" 之间"))
+	}
+
+	items, err := s.c.GetTops(s.dbc, entity, t1, t2, limit)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &insightspb.GetTopsResponse{}
+
+	for _, item := range items {
+		top := &insightspb.TopItem{Id: item.ID, Count: int64(item.Count)}
+
+		switch v := item.Item.(type) {
+		case *Track:
+			top.Item = &insightspb.TopItem_Track{Track: grpcTrack(v)}
+		case *Artist:
+			top.Item = &insightspb.TopItem_Artist{Artist: grpcArtist(v)}
+		case *Album:
+			top.Item = &insightspb.TopItem_Album{Album: grpcAlbum(v)}
+		}
+
+		resp.Items = append(resp.Items, top)
+	}
+
+	return resp, nil
+}
+
+func (s *grpcService) GetHourlyCounts(ctx context.Context, req *insightspb.GetHourlyCountsRequest) (*insightspb.GetHourlyCountsResponse, error) {
+	counts, err := s.c.GetHourlyPlayBackCounts(s.dbc)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &insightspb.GetHourlyCountsResponse{Counts: make([]int64, 24)}
+
+	for hour := range resp.Counts {
+		resp.Counts[hour] = int64(counts[hour])
+	}
+
+	return resp, nil
+}
+
+func (s *grpcService) GetDailyCounts(ctx context.Context, req *insightspb.GetDailyCountsRequest) (*insightspb.GetDailyCountsResponse, error) {
+	t1, t2, err := parseDateRange(req.From, req.To)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	ranges, err := s.c.getDailyRanges(s.dbc, t1, t2)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &insightspb.GetDailyCountsResponse{}
+
+	for _, r := range ranges {
+		resp.Days = append(resp.Days, &insightspb.DailyCount{Date: r.Date, Count: int64(r.Count), Start: int64(r.Start), End: int64(r.End)})
+	}
+
+	return resp, nil
+}
+
+func (s *grpcService) GetNowPlaying(ctx context.Context, req *insightspb.GetNowPlayingRequest) (*insightspb.GetNowPlayingResponse, error) {
+	cp, err := s.c.GetCurrentlyPlayingTrack(s.dbc)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &insightspb.GetNowPlayingResponse{IsPlaying: cp != nil}
+
+	switch {
+	case cp == nil:
+	case cp.Episode != nil:
+		resp.Item = &insightspb.GetNowPlayingResponse_Episode{Episode: grpcEpisode(cp.Episode)}
+	default:
+		resp.Item = &insightspb.GetNowPlayingResponse_Track{Track: grpcTrack(&cp.Track)}
+	}
+
+	return resp, nil
+}
+
+func (s *grpcService) WatchPlays(req *insightspb.WatchPlaysRequest, stream grpc.ServerStreamingServer[insightspb.Play]) error {
+	hub := s.c.live()
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-ch:
+			if event.Type != EventNewPlay {
+				continue
+			}
+
+			for _, play := range event.Plays {
+				err := stream.Send(&insightspb.Play{
+					Id:       play.ID,
+					Type:     insightspb.PlayType_PLAY_TYPE_TRACK,
+					PlayedAt: grpcTimestamp(play.PlayedAt),
+					Item:     &insightspb.Play_Track{Track: grpcTrack(&play.Track)},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// grpcPlay 转换播放记录, 曲目信息不存在或只读模式下无法获取时 Item 为空
+func (c *Client) grpcPlay(dbc dbClient, index int64, entry PlaybackEntry) (*insightspb.Play, error) {
+	play := &insightspb.Play{
+		Index:    proto.Int64(index),
+		Id:       entry.ID,
+		Type:     insightspb.PlayType_PLAY_TYPE_TRACK,
+		PlayedAt: grpcTimestamp(entry.PlayedAt),
+		MsPlayed: int64(entry.MsPlayed),
+	}
+
+	if entry.Context != nil {
+		play.Context = &insightspb.PlaybackContext{Type: getContextType(entry.Context), Uri: entry.Context.URI}
+	}
+
+	if isEpisode(entry) {
+		play.Type = insightspb.PlayType_PLAY_TYPE_EPISODE
+
+		episode, err := c.getEpisodeCache(dbc, entry.ID)
+		if errors.Is(err, ErrNoSpotifyClient) {
+			return play, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		play.Item = &insightspb.Play_Episode{Episode: grpcEpisode(episode)}
+		return play, nil
+	}
+
+	track, err := c.getTrackCache(dbc, entry.ID)
+	if errors.Is(err, ErrNoSpotifyClient) {
+		return play, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if track != nil {
+		play.Item = &insightspb.Play_Track{Track: grpcTrack(track)}
+	}
+
+	return play, nil
+}
+
+// grpcTimestamp 解析播放记录中 time.DateTime 格式的本地时间, 无法解析时返回 nil
+func grpcTimestamp(playedAt string) *timestamppb.Timestamp {
+	t, err := time.ParseInLocation(time.DateTime, playedAt, time.Local)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// grpcDuration 解析 Track 与 Episode 中的时长, 无法解析时返回 nil
+func grpcDuration(duration string) *durationpb.Duration {
+	d, err := parseDuration(duration)
+	if err != nil {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+func grpcImages(images []spotify.Image) []*insightspb.Image {
+	var res []*insightspb.Image
+	for _, image := range images {
+		res = append(res, &insightspb.Image{Url: image.URL, Width: int32(image.Width), Height: int32(image.Height)})
+	}
+	return res
+}
+
+func grpcArtist(a *Artist) *insightspb.Artist {
+	return &insightspb.Artist{
+		Id:         a.ID,
+		Name:       a.Name,
+		Popularity: int32(a.Popularity),
+		Genres:     a.Genres,
+		Followers:  int32(a.Followers),
+		Images:     grpcImages(a.Images),
+	}
+}
+
+func grpcArtists(artists []Artist) []*insightspb.Artist {
+	var res []*insightspb.Artist
+	for i := range artists {
+		res = append(res, grpcArtist(&artists[i]))
+	}
+	return res
+}
+
+func grpcAlbum(a *Album) *insightspb.Album {
+	return &insightspb.Album{
+		Id:          a.ID,
+		Name:        a.Name,
+		Artists:     grpcArtists(a.Artists),
+		Images:      grpcImages(a.Images),
+		ReleaseDate: a.ReleaseDate,
+		TotalTracks: int32(a.TotalTracks),
+		Popularity:  int32(a.Popularity),
+	}
+}
+
+func grpcTrack(t *Track) *insightspb.Track {
+	return &insightspb.Track{
+		Id:         t.ID,
+		Name:       t.Name,
+		Album:      grpcAlbum(&t.Album),
+		Artists:    grpcArtists(t.Artists),
+		Duration:   grpcDuration(t.Duration),
+		Popularity: int32(t.Popularity),
+		Isrc:       t.ISRC,
+	}
+}
+
+func grpcEpisode(e *Episode) *insightspb.Episode {
+	return &insightspb.Episode{
+		Id:   e.ID,
+		Name: e.Name,
+		Show: &insightspb.Show{
+			Id:        e.Show.ID,
+			Name:      e.Show.Name,
+			Publisher: e.Show.Publisher,
+			MediaType: e.Show.MediaType,
+			Images:    grpcImages(e.Show.Images),
+		},
+		Duration:    grpcDuration(e.Duration),
+		ReleaseDate: e.ReleaseDate,
+		Images:      grpcImages(e.Images),
+	}
+}
@@ -0,0 +1,498 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// PlaybackEntry 的 Source 字段
+const (
+	EntrySourceRecentlyPlayed = ""
+	EntrySourceSpotifyExport  = "spotify-export"
+)
+
+// streamingHistoryRecord 是 Spotify 数据导出(Extended streaming history)中 Streaming_History_Audio_*.json 的一条记录
+type streamingHistoryRecord struct {
+	TS              string `json:"ts"` // 播放结束的 UTC 时间
+	Platform        string `json:"platform"`
+	MsPlayed        int    `json:"ms_played"`
+	TrackName       string `json:"master_metadata_track_name"`
+	ArtistName      string `json:"master_metadata_album_artist_name"`
+	AlbumName       string `json:"master_metadata_album_album_name"`
+	TrackURI        string `json:"spotify_track_uri"`
+	EpisodeName     string `json:"episode_name"`
+	EpisodeShowName string `json:"episode_show_name"`
+	EpisodeURI      string `json:"spotify_episode_uri"`
+	ReasonStart     string `json:"reason_start"`
+	ReasonEnd       string `json:"reason_end"`
+	Shuffle         bool   `json:"shuffle"`
+	Skipped         bool   `json:"skipped"`
+}
+
+// importedNames 是导入的记录中附带的名称, 无法从 Spotify 获取信息时以名称存储
+type importedNames struct {
+	name       string
+	artistName string
+	albumName  string // 单集为播客名称
+}
+
+// minImportedMsPlayed 与 Spotify 的统计标准相同, 播放不足 30 秒的曲目不计入收听量
+const minImportedMsPlayed = 30000
+
+// ImportExtendedStreamingHistory 导入 Spotify 数据导出中的 Extended streaming history JSON 文件, 返回新增的播放记录数量
+// 与已有的播放记录按时间顺序合并, 曲目 ID 与播放时间相差 30 秒以内的视为重复, 导入后会重新统计收听量
+// 导入期间应停止 Run, 否则新保存的最近播放可能会丢失
+func (c *Client) ImportExtendedStreamingHistory(dbc dbClient, paths ...string) (int, error) {
+	var entries []PlaybackEntry
+	names := map[string]importedNames{}
+
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+
+		var records []streamingHistoryRecord
+
+		err = json.Unmarshal(b, &records)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, record := range records {
+			entry, n, err := convertStreamingHistoryRecord(record)
+			if err != nil {
+				return 0, err
+			}
+
+			if entry == nil {
+				continue
+			}
+
+			entries = append(entries, *entry)
+			names[entry.ID] = n
+		}
+
+		slog.Debug("读取 Spotify 数据导出成功", "文件", path, "记录", len(records))
+	}
+
+	entries, err := c.resolveImportedEntries(dbc, entries, names)
+	if err != nil {
+		return 0, err
+	}
+
+	imported, err := mergePlaybackEntries(dbc, entries, time.Second*30)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("导入 Spotify 数据导出成功", "新增", imported, "重复", len(entries)-imported)
+
+	if imported == 0 {
+		return 0, nil
+	}
+
+	return imported, c.RebuildAggregates(dbc)
+}
+
+// convertStreamingHistoryRecord 若记录不是有效的播放会返回 nil
+func convertStreamingHistoryRecord(record streamingHistoryRecord) (*PlaybackEntry, importedNames, error) {
+	ts, err := time.Parse(time.RFC3339, record.TS)
+	if err != nil {
+		return nil, importedNames{}, err
+	}
+
+	entry := &PlaybackEntry{
+		PlayedAt:    ts.Local().Format(time.DateTime),
+		MsPlayed:    record.MsPlayed,
+		Source:      EntrySourceSpotifyExport,
+		State:       &PlaybackState{Shuffle: record.Shuffle},
+		Skipped:     record.Skipped,
+		ReasonStart: record.ReasonStart,
+		ReasonEnd:   record.ReasonEnd,
+		Platform:    record.Platform,
+	}
+
+	switch {
+	case record.EpisodeURI != "":
+		if record.MsPlayed <= 0 {
+			return nil, importedNames{}, nil
+		}
+
+		entry.ID = uriToID(record.EpisodeURI)
+		entry.Type = EntryTypeEpisode
+
+		return entry, importedNames{record.EpisodeName, "", record.EpisodeShowName}, nil
+	case record.TrackName != "":
+		if record.MsPlayed < minImportedMsPlayed {
+			return nil, importedNames{}, nil
+		}
+
+		// 本地文件没有 URI
+		if record.TrackURI == "" {
+			entry.ID = localID("track", record.TrackName, record.ArtistName, record.AlbumName)
+		} else {
+			entry.ID = uriToID(record.TrackURI)
+		}
+
+		return entry, importedNames{record.TrackName, record.ArtistName, record.AlbumName}, nil
+	}
+
+	// 有声书等其它类型
+	return nil, importedNames{}, nil
+}
+
+func uriToID(uri string) string {
+	return uri[strings.LastIndex(uri, ":")+1:]
+}
+
+// resolveImportedEntries 通过缓存获取导入的曲目与单集的信息, 无法获取的以名称存储, 曲目 ID 会替换为重链接前的 ID
+func (c *Client) resolveImportedEntries(dbc dbClient, entries []PlaybackEntry, names map[string]importedNames) ([]PlaybackEntry, error) {
+	var missingTrackIDs []string
+	seen := map[string]bool{}
+
+	for i, entry := range entries {
+		if seen[entry.ID] {
+			continue
+		}
+		seen[entry.ID] = true
+
+		if isEpisode(entry) {
+			_, err := c.getEpisodeCache(dbc, entry.ID)
+			if err == nil {
+				continue
+			}
+
+			if !isNotFound(err) {
+				return nil, err
+			}
+
+			n := names[entry.ID]
+			slog.Warn("无法从 Spotify 获取单集信息, 以名称存储", "名称", n.name, "播客", n.albumName, "ID", entry.ID)
+
+			err = saveEpisodeByNames(dbc, entry.ID, n.name, n.albumName)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if isLocalID(entry.ID) {
+			n := names[entry.ID]
+
+			err := saveTrackByNames(dbc, entry.ID, n.name, []string{n.artistName}, n.albumName, 0)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		canonicalID, err := getCanonicalTrackID(dbc, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].ID = canonicalID
+
+		exists, err := dbc.CheckIfMapFieldExists("spotify-ids", canonicalID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			missingTrackIDs = append(missingTrackIDs, canonicalID)
+		}
+	}
+
+	for chunk := range slices.Chunk(missingTrackIDs, 50) {
+		ids := make([]spotify.ID, len(chunk))
+		for i, id := range chunk {
+			ids[i] = spotify.ID(id)
+		}
+
+		tracks, err := c.C.GetTracks(c.ctx(dbc), ids, spotify.Market(spotify.MarketFromToken))
+		if err != nil {
+			return nil, err
+		}
+
+		for i, track := range tracks {
+			id := chunk[i]
+
+			if track == nil {
+				n := names[id]
+				slog.Warn("无法从 Spotify 获取曲目信息, 以名称存储", "名称", n.name, "艺术家", n.artistName, "ID", id)
+
+				err = saveTrackByNames(dbc, id, n.name, []string{n.artistName}, n.albumName, 0)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			convertedTrack, err := c.convertTrack(dbc, track)
+			if err != nil {
+				return nil, err
+			}
+
+			if track.ID.String() != id {
+				err = saveLinkedTrackID(dbc, id, track.ID.String())
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			err = saveID(dbc, id, convertedTrack.toMap())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		slog.Debug("同步并存储导入的曲目信息成功", "数量", len(chunk))
+	}
+
+	// 重链接后的 ID 在第一次遍历时可能尚未记录
+	for i, entry := range entries {
+		if isEpisode(entry) || isLocalID(entry.ID) {
+			continue
+		}
+
+		canonicalID, err := getCanonicalTrackID(dbc, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].ID = canonicalID
+	}
+
+	return entries, nil
+}
+
+// saveEpisodeByNames 只以名称存储单集信息, 播客使用生成的 ID
+func saveEpisodeByNames(dbc dbClient, episodeID, name, showName string) error {
+	showID := localID("show", showName)
+
+	err := saveID(dbc, showID, &ShowMap{Name: showName})
+	if err != nil {
+		return err
+	}
+
+	return saveID(dbc, episodeID, &EpisodeMap{ShowID: showID, Name: name, Duration: "00:00:00"})
+}
+
+func isNotFound(err error) bool {
+	var spotifyErr spotify.Error
+	return errors.As(err, &spotifyErr) && (spotifyErr.Status == http.StatusNotFound || spotifyErr.Status == http.StatusBadRequest)
+}
+
+// mergePlaybackEntries 将 entries 按时间顺序合并进播放记录并返回新增的数量
+// 同一 ID 且播放时间相差 tolerance 以内的记录视为重复, 已有的记录保持不变
+func mergePlaybackEntries(dbc dbClient, entries []PlaybackEntry, tolerance time.Duration) (int, error) {
+	playbackHistory, err := dbc.GetSlice("playback-history", 0, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	type rawEntry struct {
+		playedAt string
+		raw      string
+	}
+
+	merged := make([]rawEntry, 0, len(playbackHistory)+len(entries))
+	playedTimes := map[string][]time.Time{}
+
+	for _, raw := range playbackHistory {
+		pe := PlaybackEntry{}
+
+		err = json.Unmarshal([]byte(raw), &pe)
+		if err != nil {
+			return 0, err
+		}
+
+		playedAt, err := time.Parse(time.DateTime, pe.PlayedAt)
+		if err != nil {
+			return 0, err
+		}
+
+		merged = append(merged, rawEntry{pe.PlayedAt, raw})
+		playedTimes[pe.ID] = append(playedTimes[pe.ID], playedAt)
+	}
+
+	isDuplicate := func(id string, playedAt time.Time) bool {
+		for _, t := range playedTimes[id] {
+			if playedAt.Sub(t).Abs() <= tolerance {
+				return true
+			}
+		}
+		return false
+	}
+
+	imported := 0
+
+	for _, entry := range entries {
+		playedAt, err := time.Parse(time.DateTime, entry.PlayedAt)
+		if err != nil {
+			return 0, err
+		}
+
+		if isDuplicate(entry.ID, playedAt) {
+			continue
+		}
+
+		j, err := json.Marshal(&entry)
+		if err != nil {
+			return 0, err
+		}
+
+		merged = append(merged, rawEntry{entry.PlayedAt, string(j)})
+		playedTimes[entry.ID] = append(playedTimes[entry.ID], playedAt)
+		imported++
+	}
+
+	if imported == 0 {
+		return 0, nil
+	}
+
+	slices.SortStableFunc(merged, func(a, b rawEntry) int {
+		return strings.Compare(a.playedAt, b.playedAt)
+	})
+
+	values := make([]string, len(merged))
+	for i, e := range merged {
+		values[i] = e.raw
+	}
+
+	err = rewritePlaybackHistory(dbc, playbackHistory, values)
+	if err != nil {
+		return 0, err
+	}
+
+	return imported, nil
+}
+
+// 重写播放记录时先完整写入 playbackHistoryRewriteKey, 完成后在 playbackHistoryRewriteLenKey 中记录数量, 再替换 playback-history
+const (
+	playbackHistoryRewriteKey    = "playback-history-rewrite"
+	playbackHistoryRewriteLenKey = "playback-history-rewrite-len"
+)
+
+// rewritePlaybackHistory 以 values 替换整个播放记录, original 为读取到的原播放记录
+// 替换失败时会写回原播放记录, 写回也失败时由 Run 启动时从 playback-history-rewrite 恢复, 见 restorePlaybackHistory
+// 期间追加的播放记录会保留在最后
+func rewritePlaybackHistory(dbc dbClient, original, values []string) error {
+	err := replaceSlice(dbc, playbackHistoryRewriteKey, values)
+	if err != nil {
+		return err
+	}
+
+	n, err := dbc.GetSliceLen("playback-history")
+	if err != nil {
+		return err
+	}
+
+	if n > int64(len(original)) {
+		appended, err := dbc.GetSlice("playback-history", int64(len(original)), -1)
+		if err != nil {
+			return err
+		}
+
+		err = dbc.AppendSlice(playbackHistoryRewriteKey, appended)
+		if err != nil {
+			return err
+		}
+
+		original = slices.Concat(original, appended)
+		values = slices.Concat(values, appended)
+	}
+
+	err = dbc.SetString(playbackHistoryRewriteLenKey, strconv.Itoa(len(values)), nil)
+	if err != nil {
+		return err
+	}
+
+	err = replaceSlice(dbc, "playback-history", values)
+	if err != nil {
+		restoreErr := replaceSlice(dbc, "playback-history", original)
+		if restoreErr != nil {
+			slog.Error("写回原播放记录失败, 下次运行 Run 时会从 "+playbackHistoryRewriteKey+" 恢复", "error", restoreErr)
+			return err
+		}
+
+		return errors.Join(err, clearPlaybackHistoryRewrite(dbc))
+	}
+
+	return clearPlaybackHistoryRewrite(dbc)
+}
+
+func clearPlaybackHistoryRewrite(dbc dbClient) error {
+	err := dbc.Delete(playbackHistoryRewriteLenKey)
+	if err != nil {
+		return err
+	}
+
+	return dbc.Delete(playbackHistoryRewriteKey)
+}
+
+// restorePlaybackHistory 若上一次重写播放记录已经写入 playback-history-rewrite 但没有完成替换, 以其替换播放记录并重新统计收听量
+func (c *Client) restorePlaybackHistory(dbc dbClient) error {
+	s, err := dbc.GetString(playbackHistoryRewriteLenKey)
+	if err != nil {
+		return err
+	}
+
+	// 没有开始替换, 删除可能未写完的 playback-history-rewrite
+	if s == "" {
+		return dbc.Delete(playbackHistoryRewriteKey)
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+
+	values, err := dbc.GetSlice(playbackHistoryRewriteKey, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	if len(values) != n {
+		return errors.New("playback-history-rewrite 的数量与记录的不一致, 需要手动检查: " + strconv.Itoa(len(values)) + " != " + s)
+	}
+
+	slog.Warn("上一次重写播放记录没有完成, 从 "+playbackHistoryRewriteKey+" 恢复", "数量", n)
+
+	err = replaceSlice(dbc, "playback-history", values)
+	if err != nil {
+		return err
+	}
+
+	err = c.RebuildAggregates(dbc)
+	if err != nil {
+		return err
+	}
+
+	return clearPlaybackHistoryRewrite(dbc)
+}
+
+// replaceSlice 删除 key 后分批写入 values
+func replaceSlice(dbc dbClient, key string, values []string) error {
+	err := dbc.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range slices.Chunk(values, 1000) {
+		err = dbc.AppendSlice(key, chunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,537 @@
+package spotify
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+const EntrySourceLastFM = "lastfm"
+
+// Scrobble 是 Last.fm 导出中的一次收听, Time 为开始播放的时间
+type Scrobble struct {
+	Artist string
+	Album  string
+	Track  string
+	Time   time.Time
+}
+
+// lastFMMatch 是存到数据库字典 lastfm-matches 中的匹配结果, 以 lastFMMatchKey 为键
+type lastFMMatch struct {
+	ID        string `json:"id"`
+	Ambiguous bool   `json:"ambiguous"`
+}
+
+// LastFMMatchReview 是需要人工确认的匹配, 存到数据库字典 lastfm-match-reviews 中, 以 Key 为键, 已确认的值为空字符串
+type LastFMMatchReview struct {
+	Key           string   `json:"key"`
+	Artist        string   `json:"artist"`
+	Album         string   `json:"album"`
+	Track         string   `json:"track"`
+	ChosenID      string   `json:"chosen_id"`
+	CandidatesIDs []string `json:"candidates_ids"`
+}
+
+func lastFMMatchKey(artist, album, track string) string {
+	return strings.ToLower(artist + "\x00" + album + "\x00" + track)
+}
+
+// ImportLastFMScrobbles 导入 Last.fm 的收听记录导出并返回新增的播放记录数量, 支持 CSV 与 JSON 格式:
+// CSV: 带表头的 uts,utc_time,artist,artist_mbid,album,album_mbid,track,track_mbid, 或不带表头的 artist,album,track,date(02 Jan 2006 15:04, UTC)
+// JSON: user.getRecentTracks 返回的 track 数组, 或由多页 track 数组组成的数组
+// 通过搜索匹配 Spotify 曲目, 匹配结果会被缓存, 不确定的匹配会记录到 GetLastFMMatchReviews 中, 无法匹配的以名称存储
+// Last.fm 记录的是开始播放的时间, 匹配后会加上曲目时长, 与已有的播放记录相差 90 秒以内的视为重复, 导入期间应停止 Run
+func (c *Client) ImportLastFMScrobbles(dbc dbClient, paths ...string) (int, error) {
+	var scrobbles []Scrobble
+
+	for _, path := range paths {
+		s, err := readLastFMScrobbles(path)
+		if err != nil {
+			return 0, err
+		}
+
+		slog.Debug("读取 Last.fm 导出成功", "文件", path, "记录", len(s))
+
+		scrobbles = append(scrobbles, s...)
+	}
+
+	var entries []PlaybackEntry
+
+	for i, scrobble := range scrobbles {
+		track, err := c.matchScrobble(dbc, scrobble)
+		if err != nil {
+			return 0, err
+		}
+
+		duration, err := parseDuration(track.Duration)
+		if err != nil {
+			return 0, err
+		}
+
+		entries = append(entries, PlaybackEntry{
+			ID:       track.ID,
+			PlayedAt: scrobble.Time.Add(duration).Local().Format(time.DateTime),
+			Source:   EntrySourceLastFM,
+			MatchKey: lastFMMatchKey(scrobble.Artist, scrobble.Album, scrobble.Track),
+		})
+
+		if (i+1)%500 == 0 {
+			slog.Info("正在匹配 Last.fm 收听记录", "进度", i+1, "总共", len(scrobbles))
+		}
+	}
+
+	imported, err := mergePlaybackEntries(dbc, entries, time.Second*90)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("导入 Last.fm 收听记录成功", "新增", imported, "重复", len(entries)-imported)
+
+	if imported == 0 {
+		return 0, nil
+	}
+
+	return imported, c.RebuildAggregates(dbc)
+}
+
+func readLastFMScrobbles(path string) ([]Scrobble, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return readLastFMScrobblesJSON(f)
+	}
+
+	return readLastFMScrobblesCSV(f)
+}
+
+func readLastFMScrobblesCSV(r io.Reader) ([]Scrobble, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var scrobbles []Scrobble
+
+	// 带表头
+	if slices.Contains(records[0], "uts") {
+		columns := map[string]int{}
+		for i, name := range records[0] {
+			columns[name] = i
+		}
+
+		for _, column := range []string{"uts", "artist", "album", "track"} {
+			if _, ok := columns[column]; !ok {
+				return nil, fmt.Errorf("Last.fm 导出缺少 %s 列", column)
+			}
+		}
+
+		for _, record := range records[1:] {
+			uts, err := strconv.ParseInt(record[columns["uts"]], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			scrobbles = append(scrobbles, Scrobble{
+				Artist: record[columns["artist"]],
+				Album:  record[columns["album"]],
+				Track:  record[columns["track"]],
+				Time:   time.Unix(uts, 0),
+			})
+		}
+
+		return scrobbles, nil
+	}
+
+	for _, record := range records {
+		if len(record) < 4 {
+			return nil, fmt.Errorf("Last.fm 导出格式错误: %v", record)
+		}
+
+		t, err := time.Parse("02 Jan 2006 15:04", record[3])
+		if err != nil {
+			return nil, err
+		}
+
+		scrobbles = append(scrobbles, Scrobble{Artist: record[0], Album: record[1], Track: record[2], Time: t})
+	}
+
+	return scrobbles, nil
+}
+
+type lastFMTrack struct {
+	Artist struct {
+		Text string `json:"#text"`
+		Name string `json:"name"`
+	} `json:"artist"`
+	Album struct {
+		Text string `json:"#text"`
+	} `json:"album"`
+	Name string `json:"name"`
+	Date *struct {
+		UTS string `json:"uts"`
+	} `json:"date"`
+}
+
+func readLastFMScrobblesJSON(r io.Reader) ([]Scrobble, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []lastFMTrack
+
+	err = json.Unmarshal(b, &tracks)
+	if err != nil {
+		var pages [][]lastFMTrack
+
+		if json.Unmarshal(b, &pages) != nil {
+			return nil, err
+		}
+
+		for _, page := range pages {
+			tracks = append(tracks, page...)
+		}
+	}
+
+	var scrobbles []Scrobble
+
+	for _, track := range tracks {
+		// 正在播放的曲目没有日期
+		if track.Date == nil {
+			continue
+		}
+
+		uts, err := strconv.ParseInt(track.Date.UTS, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		artist := track.Artist.Text
+		if artist == "" {
+			artist = track.Artist.Name
+		}
+
+		scrobbles = append(scrobbles, Scrobble{Artist: artist, Album: track.Album.Text, Track: track.Name, Time: time.Unix(uts, 0)})
+	}
+
+	return scrobbles, nil
+}
+
+// matchScrobble 返回收听记录对应的曲目, 优先使用缓存的匹配结果, 无法匹配时以名称存储
+func (c *Client) matchScrobble(dbc dbClient, scrobble Scrobble) (*Track, error) {
+	key := lastFMMatchKey(scrobble.Artist, scrobble.Album, scrobble.Track)
+
+	s, err := dbc.GetMapStr("lastfm-matches", key)
+	if err != nil {
+		return nil, err
+	}
+
+	if s != "" {
+		match := &lastFMMatch{}
+
+		err = json.Unmarshal([]byte(s), match)
+		if err != nil {
+			return nil, err
+		}
+
+		track, err := c.getTrackCache(dbc, match.ID)
+		if err != nil || track != nil {
+			return track, err
+		}
+	}
+
+	match, err := c.searchScrobble(dbc, scrobble, key)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := json.Marshal(match)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbc.SetMap("lastfm-matches", key, string(j))
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := c.getTrackCache(dbc, match.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if track == nil {
+		return nil, errors.New("无法获取匹配的曲目信息: " + match.ID)
+	}
+
+	return track, nil
+}
+
+// searchScrobble 搜索匹配的曲目, 名称与艺术家均一致且专辑一致或唯一时视为确定的匹配, 否则取最接近的结果并记录待确认
+func (c *Client) searchScrobble(dbc dbClient, scrobble Scrobble, key string) (*lastFMMatch, error) {
+	searchResults, err := c.C.Search(c.ctx(dbc), "artist:"+scrobble.Artist+" track:"+scrobble.Track, spotify.SearchTypeTrack, spotify.Limit(10))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []spotify.FullTrack
+	var results []spotify.FullTrack
+	bestScore := 0
+
+	if searchResults.Tracks != nil {
+		results = searchResults.Tracks.Tracks
+	}
+
+	for _, result := range results {
+		score := scoreScrobbleMatch(result, scrobble)
+
+		if score > bestScore {
+			bestScore = score
+			candidates = []spotify.FullTrack{result}
+		} else if score == bestScore && score > 0 {
+			candidates = append(candidates, result)
+		}
+	}
+
+	// 名称与艺术家都不一致
+	if bestScore < 2 {
+		slog.Warn("无法匹配 Last.fm 收听记录, 以名称存储", "名称", scrobble.Track, "艺术家", scrobble.Artist, "专辑", scrobble.Album)
+
+		id := localID("track", scrobble.Track, scrobble.Artist, scrobble.Album)
+
+		err = saveTrackByNames(dbc, id, scrobble.Track, []string{scrobble.Artist}, scrobble.Album, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		err = saveLastFMMatchReview(dbc, scrobble, key, id, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lastFMMatch{ID: id, Ambiguous: true}, nil
+	}
+
+	match := &lastFMMatch{ID: candidates[0].ID.String()}
+
+	if bestScore < 4 || len(candidates) > 1 && (scrobble.Album == "" || bestScore < 5) {
+		match.Ambiguous = true
+
+		err = saveLastFMMatchReview(dbc, scrobble, key, match.ID, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return match, nil
+}
+
+// saveLastFMMatchReview 记录待确认的匹配, 无法匹配时 chosenID 为本地 ID, candidates 为空
+func saveLastFMMatchReview(dbc dbClient, scrobble Scrobble, key, chosenID string, candidates []spotify.FullTrack) error {
+	review := &LastFMMatchReview{
+		Key:           key,
+		Artist:        scrobble.Artist,
+		Album:         scrobble.Album,
+		Track:         scrobble.Track,
+		ChosenID:      chosenID,
+		CandidatesIDs: []string{},
+	}
+
+	for _, candidate := range candidates {
+		review.CandidatesIDs = append(review.CandidatesIDs, candidate.ID.String())
+	}
+
+	j, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap("lastfm-match-reviews", key, string(j))
+}
+
+// scoreScrobbleMatch 名称一致 2 分, 艺术家一致 2 分, 专辑一致 1 分, 名称按 normalizeTrackName 比较
+func scoreScrobbleMatch(track spotify.FullTrack, scrobble Scrobble) int {
+	score := 0
+
+	if normalizeTrackName(track.Name) == normalizeTrackName(scrobble.Track) {
+		score += 2
+	}
+
+	for _, artist := range track.Artists {
+		if strings.EqualFold(artist.Name, scrobble.Artist) {
+			score += 2
+			break
+		}
+	}
+
+	if scrobble.Album != "" && strings.EqualFold(track.Album.Name, scrobble.Album) {
+		score++
+	}
+
+	return score
+}
+
+// GetLastFMMatchReviews 返回导入 Last.fm 收听记录时不确定的匹配
+func (c *Client) GetLastFMMatchReviews(dbc dbClient) ([]LastFMMatchReview, error) {
+	all, err := dbc.GetMapAll("lastfm-match-reviews")
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []LastFMMatchReview
+
+	for _, s := range all {
+		// 已确认
+		if s == "" {
+			continue
+		}
+
+		review := LastFMMatchReview{}
+
+		err = json.Unmarshal([]byte(s), &review)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	slices.SortFunc(reviews, func(a, b LastFMMatchReview) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return reviews, nil
+}
+
+// ResolveLastFMMatchReview 确认一个待确认的匹配, trackID 为正确的曲目 ID, 只替换以此匹配导入的播放记录, 有替换时重新统计收听量
+func (c *Client) ResolveLastFMMatchReview(dbc dbClient, key, trackID string) error {
+	all, err := dbc.GetMapAll("lastfm-match-reviews")
+	if err != nil {
+		return err
+	}
+
+	s := all[key]
+	if s == "" {
+		return errors.New("不存在此待确认的匹配: " + key)
+	}
+
+	review := LastFMMatchReview{}
+
+	err = json.Unmarshal([]byte(s), &review)
+	if err != nil {
+		return err
+	}
+
+	track, err := c.getTrackCache(dbc, trackID)
+	if err != nil {
+		return err
+	}
+
+	if track == nil {
+		return errors.New("无法获取曲目信息: " + trackID)
+	}
+
+	j, err := json.Marshal(&lastFMMatch{ID: track.ID})
+	if err != nil {
+		return err
+	}
+
+	err = dbc.SetMap("lastfm-matches", key, string(j))
+	if err != nil {
+		return err
+	}
+
+	replaced, err := replaceMatchedEntriesID(dbc, key, track.ID)
+	if err != nil {
+		return err
+	}
+
+	if replaced > 0 {
+		err = c.RebuildAggregates(dbc)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 数据库没有删除字典字段的操作, 先标记为已确认, 全部确认后才删除整个字典, 以免失败时丢失其它待确认的匹配
+	err = dbc.SetMap("lastfm-match-reviews", key, "")
+	if err != nil {
+		return err
+	}
+
+	for k, v := range all {
+		if k != key && v != "" {
+			return nil
+		}
+	}
+
+	return dbc.Delete("lastfm-match-reviews")
+}
+
+// replaceMatchedEntriesID 将以 key 匹配导入的 Last.fm 播放记录的曲目 ID 替换为 newID, 返回替换的数量
+func replaceMatchedEntriesID(dbc dbClient, key, newID string) (int, error) {
+	playbackHistory, err := dbc.GetSlice("playback-history", 0, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	replaced := 0
+
+	values := slices.Clone(playbackHistory)
+
+	for i, raw := range playbackHistory {
+		pe := PlaybackEntry{}
+
+		err = json.Unmarshal([]byte(raw), &pe)
+		if err != nil {
+			return 0, err
+		}
+
+		if pe.Source != EntrySourceLastFM || pe.MatchKey != key || pe.ID == newID {
+			continue
+		}
+
+		pe.ID = newID
+
+		j, err := json.Marshal(&pe)
+		if err != nil {
+			return 0, err
+		}
+
+		values[i] = string(j)
+		replaced++
+	}
+
+	if replaced == 0 {
+		return 0, nil
+	}
+
+	err = rewritePlaybackHistory(dbc, playbackHistory, values)
+	if err != nil {
+		return 0, err
+	}
+
+	return replaced, nil
+}
@@ -0,0 +1,4 @@
+// Package insightspb 是 InsightsService 的 protobuf 消息与 gRPC 代码, 由 spotify_insights.proto 生成, 服务端实现见 spotify.Client.GRPCService
+package insightspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative spotify_insights.proto
@@ -0,0 +1,1790 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: spotify_insights.proto
+
+package insightspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Order int32
+
+const (
+	Order_ORDER_UNSPECIFIED Order = 0 // 同 ORDER_ASC
+	Order_ORDER_ASC         Order = 1 // 从最早开始
+	Order_ORDER_DESC        Order = 2 // 从最新开始
+)
+
+// Enum value maps for Order.
+var (
+	Order_name = map[int32]string{
+		0: "ORDER_UNSPECIFIED",
+		1: "ORDER_ASC",
+		2: "ORDER_DESC",
+	}
+	Order_value = map[string]int32{
+		"ORDER_UNSPECIFIED": 0,
+		"ORDER_ASC":         1,
+		"ORDER_DESC":        2,
+	}
+)
+
+func (x Order) Enum() *Order {
+	p := new(Order)
+	*p = x
+	return p
+}
+
+func (x Order) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Order) Descriptor() protoreflect.EnumDescriptor {
+	return file_spotify_insights_proto_enumTypes[0].Descriptor()
+}
+
+func (Order) Type() protoreflect.EnumType {
+	return &file_spotify_insights_proto_enumTypes[0]
+}
+
+func (x Order) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Order.Descriptor instead.
+func (Order) EnumDescriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{0}
+}
+
+type ChartEntity int32
+
+const (
+	ChartEntity_CHART_ENTITY_UNSPECIFIED ChartEntity = 0 // 同 CHART_ENTITY_TRACKS
+	ChartEntity_CHART_ENTITY_TRACKS      ChartEntity = 1
+	ChartEntity_CHART_ENTITY_ARTISTS     ChartEntity = 2
+	ChartEntity_CHART_ENTITY_ALBUMS      ChartEntity = 3
+)
+
+// Enum value maps for ChartEntity.
+var (
+	ChartEntity_name = map[int32]string{
+		0: "CHART_ENTITY_UNSPECIFIED",
+		1: "CHART_ENTITY_TRACKS",
+		2: "CHART_ENTITY_ARTISTS",
+		3: "CHART_ENTITY_ALBUMS",
+	}
+	ChartEntity_value = map[string]int32{
+		"CHART_ENTITY_UNSPECIFIED": 0,
+		"CHART_ENTITY_TRACKS":      1,
+		"CHART_ENTITY_ARTISTS":     2,
+		"CHART_ENTITY_ALBUMS":      3,
+	}
+)
+
+func (x ChartEntity) Enum() *ChartEntity {
+	p := new(ChartEntity)
+	*p = x
+	return p
+}
+
+func (x ChartEntity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChartEntity) Descriptor() protoreflect.EnumDescriptor {
+	return file_spotify_insights_proto_enumTypes[1].Descriptor()
+}
+
+func (ChartEntity) Type() protoreflect.EnumType {
+	return &file_spotify_insights_proto_enumTypes[1]
+}
+
+func (x ChartEntity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChartEntity.Descriptor instead.
+func (ChartEntity) EnumDescriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{1}
+}
+
+type PlayType int32
+
+const (
+	PlayType_PLAY_TYPE_UNSPECIFIED PlayType = 0
+	PlayType_PLAY_TYPE_TRACK       PlayType = 1
+	PlayType_PLAY_TYPE_EPISODE     PlayType = 2
+)
+
+// Enum value maps for PlayType.
+var (
+	PlayType_name = map[int32]string{
+		0: "PLAY_TYPE_UNSPECIFIED",
+		1: "PLAY_TYPE_TRACK",
+		2: "PLAY_TYPE_EPISODE",
+	}
+	PlayType_value = map[string]int32{
+		"PLAY_TYPE_UNSPECIFIED": 0,
+		"PLAY_TYPE_TRACK":       1,
+		"PLAY_TYPE_EPISODE":     2,
+	}
+)
+
+func (x PlayType) Enum() *PlayType {
+	p := new(PlayType)
+	*p = x
+	return p
+}
+
+func (x PlayType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PlayType) Descriptor() protoreflect.EnumDescriptor {
+	return file_spotify_insights_proto_enumTypes[2].Descriptor()
+}
+
+func (PlayType) Type() protoreflect.EnumType {
+	return &file_spotify_insights_proto_enumTypes[2]
+}
+
+func (x PlayType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PlayType.Descriptor instead.
+func (PlayType) EnumDescriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{2}
+}
+
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Width         int32                  `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_spotify_insights_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Image) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Image) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Image) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type Artist struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Popularity    int32                  `protobuf:"varint,3,opt,name=popularity,proto3" json:"popularity,omitempty"`
+	Genres        []string               `protobuf:"bytes,4,rep,name=genres,proto3" json:"genres,omitempty"`
+	Followers     int32                  `protobuf:"varint,5,opt,name=followers,proto3" json:"followers,omitempty"`
+	Images        []*Image               `protobuf:"bytes,6,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Artist) Reset() {
+	*x = Artist{}
+	mi := &file_spotify_insights_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Artist) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Artist) ProtoMessage() {}
+
+func (x *Artist) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Artist.ProtoReflect.Descriptor instead.
+func (*Artist) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Artist) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Artist) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Artist) GetPopularity() int32 {
+	if x != nil {
+		return x.Popularity
+	}
+	return 0
+}
+
+func (x *Artist) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *Artist) GetFollowers() int32 {
+	if x != nil {
+		return x.Followers
+	}
+	return 0
+}
+
+func (x *Artist) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type Album struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Artists       []*Artist              `protobuf:"bytes,3,rep,name=artists,proto3" json:"artists,omitempty"`
+	Images        []*Image               `protobuf:"bytes,4,rep,name=images,proto3" json:"images,omitempty"`
+	ReleaseDate   string                 `protobuf:"bytes,5,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	TotalTracks   int32                  `protobuf:"varint,6,opt,name=total_tracks,json=totalTracks,proto3" json:"total_tracks,omitempty"`
+	Popularity    int32                  `protobuf:"varint,7,opt,name=popularity,proto3" json:"popularity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Album) Reset() {
+	*x = Album{}
+	mi := &file_spotify_insights_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Album) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Album) ProtoMessage() {}
+
+func (x *Album) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Album.ProtoReflect.Descriptor instead.
+func (*Album) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Album) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Album) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Album) GetArtists() []*Artist {
+	if x != nil {
+		return x.Artists
+	}
+	return nil
+}
+
+func (x *Album) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *Album) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *Album) GetTotalTracks() int32 {
+	if x != nil {
+		return x.TotalTracks
+	}
+	return 0
+}
+
+func (x *Album) GetPopularity() int32 {
+	if x != nil {
+		return x.Popularity
+	}
+	return 0
+}
+
+type Track struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Album         *Album                 `protobuf:"bytes,3,opt,name=album,proto3" json:"album,omitempty"`
+	Artists       []*Artist              `protobuf:"bytes,4,rep,name=artists,proto3" json:"artists,omitempty"`
+	Duration      *durationpb.Duration   `protobuf:"bytes,5,opt,name=duration,proto3" json:"duration,omitempty"`
+	Popularity    int32                  `protobuf:"varint,6,opt,name=popularity,proto3" json:"popularity,omitempty"`
+	Isrc          string                 `protobuf:"bytes,7,opt,name=isrc,proto3" json:"isrc,omitempty"` // 本地文件与旧的缓存为空
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Track) Reset() {
+	*x = Track{}
+	mi := &file_spotify_insights_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Track) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Track) ProtoMessage() {}
+
+func (x *Track) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Track.ProtoReflect.Descriptor instead.
+func (*Track) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Track) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Track) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Track) GetAlbum() *Album {
+	if x != nil {
+		return x.Album
+	}
+	return nil
+}
+
+func (x *Track) GetArtists() []*Artist {
+	if x != nil {
+		return x.Artists
+	}
+	return nil
+}
+
+func (x *Track) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *Track) GetPopularity() int32 {
+	if x != nil {
+		return x.Popularity
+	}
+	return 0
+}
+
+func (x *Track) GetIsrc() string {
+	if x != nil {
+		return x.Isrc
+	}
+	return ""
+}
+
+type Show struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Publisher     string                 `protobuf:"bytes,3,opt,name=publisher,proto3" json:"publisher,omitempty"`
+	MediaType     string                 `protobuf:"bytes,4,opt,name=media_type,json=mediaType,proto3" json:"media_type,omitempty"`
+	Images        []*Image               `protobuf:"bytes,5,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Show) Reset() {
+	*x = Show{}
+	mi := &file_spotify_insights_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Show) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Show) ProtoMessage() {}
+
+func (x *Show) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Show.ProtoReflect.Descriptor instead.
+func (*Show) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Show) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Show) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Show) GetPublisher() string {
+	if x != nil {
+		return x.Publisher
+	}
+	return ""
+}
+
+func (x *Show) GetMediaType() string {
+	if x != nil {
+		return x.MediaType
+	}
+	return ""
+}
+
+func (x *Show) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type Episode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Show          *Show                  `protobuf:"bytes,3,opt,name=show,proto3" json:"show,omitempty"`
+	Duration      *durationpb.Duration   `protobuf:"bytes,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	ReleaseDate   string                 `protobuf:"bytes,5,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	Images        []*Image               `protobuf:"bytes,6,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Episode) Reset() {
+	*x = Episode{}
+	mi := &file_spotify_insights_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Episode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Episode) ProtoMessage() {}
+
+func (x *Episode) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Episode.ProtoReflect.Descriptor instead.
+func (*Episode) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Episode) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Episode) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Episode) GetShow() *Show {
+	if x != nil {
+		return x.Show
+	}
+	return nil
+}
+
+func (x *Episode) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *Episode) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *Episode) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type PlaybackContext struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // album artist playlist collection
+	Uri           string                 `protobuf:"bytes,2,opt,name=uri,proto3" json:"uri,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlaybackContext) Reset() {
+	*x = PlaybackContext{}
+	mi := &file_spotify_insights_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaybackContext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaybackContext) ProtoMessage() {}
+
+func (x *PlaybackContext) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaybackContext.ProtoReflect.Descriptor instead.
+func (*PlaybackContext) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PlaybackContext) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PlaybackContext) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+// Play 是一次播放, 信息不存在(如未存储的本地文件)或只读模式下无法获取时 item 为空
+type Play struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Index    *int64                 `protobuf:"varint,1,opt,name=index,proto3,oneof" json:"index,omitempty"` // 在播放记录中的位置, 从 0 开始
+	Id       string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Type     PlayType               `protobuf:"varint,3,opt,name=type,proto3,enum=spotifyinsights.v1.PlayType" json:"type,omitempty"`
+	PlayedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=played_at,json=playedAt,proto3" json:"played_at,omitempty"`
+	MsPlayed int64                  `protobuf:"varint,5,opt,name=ms_played,json=msPlayed,proto3" json:"ms_played,omitempty"` // 只有单集与从 Spotify 数据导出导入的播放记录有
+	Context  *PlaybackContext       `protobuf:"bytes,6,opt,name=context,proto3" json:"context,omitempty"`
+	// Types that are valid to be assigned to Item:
+	//
+	//	*Play_Track
+	//	*Play_Episode
+	Item          isPlay_Item `protobuf_oneof:"item"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Play) Reset() {
+	*x = Play{}
+	mi := &file_spotify_insights_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Play) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Play) ProtoMessage() {}
+
+func (x *Play) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Play.ProtoReflect.Descriptor instead.
+func (*Play) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Play) GetIndex() int64 {
+	if x != nil && x.Index != nil {
+		return *x.Index
+	}
+	return 0
+}
+
+func (x *Play) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Play) GetType() PlayType {
+	if x != nil {
+		return x.Type
+	}
+	return PlayType_PLAY_TYPE_UNSPECIFIED
+}
+
+func (x *Play) GetPlayedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PlayedAt
+	}
+	return nil
+}
+
+func (x *Play) GetMsPlayed() int64 {
+	if x != nil {
+		return x.MsPlayed
+	}
+	return 0
+}
+
+func (x *Play) GetContext() *PlaybackContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *Play) GetItem() isPlay_Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *Play) GetTrack() *Track {
+	if x != nil {
+		if x, ok := x.Item.(*Play_Track); ok {
+			return x.Track
+		}
+	}
+	return nil
+}
+
+func (x *Play) GetEpisode() *Episode {
+	if x != nil {
+		if x, ok := x.Item.(*Play_Episode); ok {
+			return x.Episode
+		}
+	}
+	return nil
+}
+
+type isPlay_Item interface {
+	isPlay_Item()
+}
+
+type Play_Track struct {
+	Track *Track `protobuf:"bytes,7,opt,name=track,proto3,oneof"`
+}
+
+type Play_Episode struct {
+	Episode *Episode `protobuf:"bytes,8,opt,name=episode,proto3,oneof"`
+}
+
+func (*Play_Track) isPlay_Item() {}
+
+func (*Play_Episode) isPlay_Item() {}
+
+type StreamHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         int64                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"` // 为 0 时返回之后的全部播放记录
+	Order         Order                  `protobuf:"varint,3,opt,name=order,proto3,enum=spotifyinsights.v1.Order" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamHistoryRequest) Reset() {
+	*x = StreamHistoryRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamHistoryRequest) ProtoMessage() {}
+
+func (x *StreamHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamHistoryRequest.ProtoReflect.Descriptor instead.
+func (*StreamHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamHistoryRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *StreamHistoryRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *StreamHistoryRequest) GetOrder() Order {
+	if x != nil {
+		return x.Order
+	}
+	return Order_ORDER_UNSPECIFIED
+}
+
+type GetTopsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entity        ChartEntity            `protobuf:"varint,1,opt,name=entity,proto3,enum=spotifyinsights.v1.ChartEntity" json:"entity,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"` // 为 0 时为 50, 最大为 500
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTopsRequest) Reset() {
+	*x = GetTopsRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTopsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTopsRequest) ProtoMessage() {}
+
+func (x *GetTopsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTopsRequest.ProtoReflect.Descriptor instead.
+func (*GetTopsRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetTopsRequest) GetEntity() ChartEntity {
+	if x != nil {
+		return x.Entity
+	}
+	return ChartEntity_CHART_ENTITY_UNSPECIFIED
+}
+
+func (x *GetTopsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetTopsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *GetTopsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// TopItem 信息不存在时 item 为空
+type TopItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Count int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// Types that are valid to be assigned to Item:
+	//
+	//	*TopItem_Track
+	//	*TopItem_Artist
+	//	*TopItem_Album
+	Item          isTopItem_Item `protobuf_oneof:"item"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopItem) Reset() {
+	*x = TopItem{}
+	mi := &file_spotify_insights_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopItem) ProtoMessage() {}
+
+func (x *TopItem) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopItem.ProtoReflect.Descriptor instead.
+func (*TopItem) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TopItem) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TopItem) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *TopItem) GetItem() isTopItem_Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *TopItem) GetTrack() *Track {
+	if x != nil {
+		if x, ok := x.Item.(*TopItem_Track); ok {
+			return x.Track
+		}
+	}
+	return nil
+}
+
+func (x *TopItem) GetArtist() *Artist {
+	if x != nil {
+		if x, ok := x.Item.(*TopItem_Artist); ok {
+			return x.Artist
+		}
+	}
+	return nil
+}
+
+func (x *TopItem) GetAlbum() *Album {
+	if x != nil {
+		if x, ok := x.Item.(*TopItem_Album); ok {
+			return x.Album
+		}
+	}
+	return nil
+}
+
+type isTopItem_Item interface {
+	isTopItem_Item()
+}
+
+type TopItem_Track struct {
+	Track *Track `protobuf:"bytes,3,opt,name=track,proto3,oneof"`
+}
+
+type TopItem_Artist struct {
+	Artist *Artist `protobuf:"bytes,4,opt,name=artist,proto3,oneof"`
+}
+
+type TopItem_Album struct {
+	Album *Album `protobuf:"bytes,5,opt,name=album,proto3,oneof"`
+}
+
+func (*TopItem_Track) isTopItem_Item() {}
+
+func (*TopItem_Artist) isTopItem_Item() {}
+
+func (*TopItem_Album) isTopItem_Item() {}
+
+type GetTopsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*TopItem             `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTopsResponse) Reset() {
+	*x = GetTopsResponse{}
+	mi := &file_spotify_insights_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTopsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTopsResponse) ProtoMessage() {}
+
+func (x *GetTopsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTopsResponse.ProtoReflect.Descriptor instead.
+func (*GetTopsResponse) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetTopsResponse) GetItems() []*TopItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type GetHourlyCountsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHourlyCountsRequest) Reset() {
+	*x = GetHourlyCountsRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHourlyCountsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHourlyCountsRequest) ProtoMessage() {}
+
+func (x *GetHourlyCountsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHourlyCountsRequest.ProtoReflect.Descriptor instead.
+func (*GetHourlyCountsRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{12}
+}
+
+type GetHourlyCountsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Counts        []int64                `protobuf:"varint,1,rep,packed,name=counts,proto3" json:"counts,omitempty"` // 长度为 24, 下标为小时
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHourlyCountsResponse) Reset() {
+	*x = GetHourlyCountsResponse{}
+	mi := &file_spotify_insights_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHourlyCountsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHourlyCountsResponse) ProtoMessage() {}
+
+func (x *GetHourlyCountsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHourlyCountsResponse.ProtoReflect.Descriptor instead.
+func (*GetHourlyCountsResponse) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetHourlyCountsResponse) GetCounts() []int64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+type GetDailyCountsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDailyCountsRequest) Reset() {
+	*x = GetDailyCountsRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDailyCountsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDailyCountsRequest) ProtoMessage() {}
+
+func (x *GetDailyCountsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDailyCountsRequest.ProtoReflect.Descriptor instead.
+func (*GetDailyCountsRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetDailyCountsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetDailyCountsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type DailyCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Start         int64                  `protobuf:"varint,3,opt,name=start,proto3" json:"start,omitempty"` // 当天第一条播放记录的位置
+	End           int64                  `protobuf:"varint,4,opt,name=end,proto3" json:"end,omitempty"`     // 当天最后一条播放记录的位置
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DailyCount) Reset() {
+	*x = DailyCount{}
+	mi := &file_spotify_insights_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DailyCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyCount) ProtoMessage() {}
+
+func (x *DailyCount) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyCount.ProtoReflect.Descriptor instead.
+func (*DailyCount) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DailyCount) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *DailyCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *DailyCount) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *DailyCount) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type GetDailyCountsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Days          []*DailyCount          `protobuf:"bytes,1,rep,name=days,proto3" json:"days,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDailyCountsResponse) Reset() {
+	*x = GetDailyCountsResponse{}
+	mi := &file_spotify_insights_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDailyCountsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDailyCountsResponse) ProtoMessage() {}
+
+func (x *GetDailyCountsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDailyCountsResponse.ProtoReflect.Descriptor instead.
+func (*GetDailyCountsResponse) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetDailyCountsResponse) GetDays() []*DailyCount {
+	if x != nil {
+		return x.Days
+	}
+	return nil
+}
+
+type GetNowPlayingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNowPlayingRequest) Reset() {
+	*x = GetNowPlayingRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNowPlayingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNowPlayingRequest) ProtoMessage() {}
+
+func (x *GetNowPlayingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNowPlayingRequest.ProtoReflect.Descriptor instead.
+func (*GetNowPlayingRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{17}
+}
+
+type GetNowPlayingResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	IsPlaying bool                   `protobuf:"varint,1,opt,name=is_playing,json=isPlaying,proto3" json:"is_playing,omitempty"`
+	// Types that are valid to be assigned to Item:
+	//
+	//	*GetNowPlayingResponse_Track
+	//	*GetNowPlayingResponse_Episode
+	Item          isGetNowPlayingResponse_Item `protobuf_oneof:"item"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNowPlayingResponse) Reset() {
+	*x = GetNowPlayingResponse{}
+	mi := &file_spotify_insights_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNowPlayingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNowPlayingResponse) ProtoMessage() {}
+
+func (x *GetNowPlayingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNowPlayingResponse.ProtoReflect.Descriptor instead.
+func (*GetNowPlayingResponse) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetNowPlayingResponse) GetIsPlaying() bool {
+	if x != nil {
+		return x.IsPlaying
+	}
+	return false
+}
+
+func (x *GetNowPlayingResponse) GetItem() isGetNowPlayingResponse_Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *GetNowPlayingResponse) GetTrack() *Track {
+	if x != nil {
+		if x, ok := x.Item.(*GetNowPlayingResponse_Track); ok {
+			return x.Track
+		}
+	}
+	return nil
+}
+
+func (x *GetNowPlayingResponse) GetEpisode() *Episode {
+	if x != nil {
+		if x, ok := x.Item.(*GetNowPlayingResponse_Episode); ok {
+			return x.Episode
+		}
+	}
+	return nil
+}
+
+type isGetNowPlayingResponse_Item interface {
+	isGetNowPlayingResponse_Item()
+}
+
+type GetNowPlayingResponse_Track struct {
+	Track *Track `protobuf:"bytes,2,opt,name=track,proto3,oneof"`
+}
+
+type GetNowPlayingResponse_Episode struct {
+	Episode *Episode `protobuf:"bytes,3,opt,name=episode,proto3,oneof"`
+}
+
+func (*GetNowPlayingResponse_Track) isGetNowPlayingResponse_Item() {}
+
+func (*GetNowPlayingResponse_Episode) isGetNowPlayingResponse_Item() {}
+
+type WatchPlaysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPlaysRequest) Reset() {
+	*x = WatchPlaysRequest{}
+	mi := &file_spotify_insights_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPlaysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPlaysRequest) ProtoMessage() {}
+
+func (x *WatchPlaysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_spotify_insights_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPlaysRequest.ProtoReflect.Descriptor instead.
+func (*WatchPlaysRequest) Descriptor() ([]byte, []int) {
+	return file_spotify_insights_proto_rawDescGZIP(), []int{19}
+}
+
+var File_spotify_insights_proto protoreflect.FileDescriptor
+
+const file_spotify_insights_proto_rawDesc = "" +
+	"\n" +
+	"\x16spotify_insights.proto\x12\x12spotifyinsights.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"G\n" +
+	"\x05Image\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x05R\x06height\"\xb5\x01\n" +
+	"\x06Artist\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"popularity\x18\x03 \x01(\x05R\n" +
+	"popularity\x12\x16\n" +
+	"\x06genres\x18\x04 \x03(\tR\x06genres\x12\x1c\n" +
+	"\tfollowers\x18\x05 \x01(\x05R\tfollowers\x121\n" +
+	"\x06images\x18\x06 \x03(\v2\x19.spotifyinsights.v1.ImageR\x06images\"\xfa\x01\n" +
+	"\x05Album\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x124\n" +
+	"\aartists\x18\x03 \x03(\v2\x1a.spotifyinsights.v1.ArtistR\aartists\x121\n" +
+	"\x06images\x18\x04 \x03(\v2\x19.spotifyinsights.v1.ImageR\x06images\x12!\n" +
+	"\frelease_date\x18\x05 \x01(\tR\vreleaseDate\x12!\n" +
+	"\ftotal_tracks\x18\x06 \x01(\x05R\vtotalTracks\x12\x1e\n" +
+	"\n" +
+	"popularity\x18\a \x01(\x05R\n" +
+	"popularity\"\xfd\x01\n" +
+	"\x05Track\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x05album\x18\x03 \x01(\v2\x19.spotifyinsights.v1.AlbumR\x05album\x124\n" +
+	"\aartists\x18\x04 \x03(\v2\x1a.spotifyinsights.v1.ArtistR\aartists\x125\n" +
+	"\bduration\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12\x1e\n" +
+	"\n" +
+	"popularity\x18\x06 \x01(\x05R\n" +
+	"popularity\x12\x12\n" +
+	"\x04isrc\x18\a \x01(\tR\x04isrc\"\x9a\x01\n" +
+	"\x04Show\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\tpublisher\x18\x03 \x01(\tR\tpublisher\x12\x1d\n" +
+	"\n" +
+	"media_type\x18\x04 \x01(\tR\tmediaType\x121\n" +
+	"\x06images\x18\x05 \x03(\v2\x19.spotifyinsights.v1.ImageR\x06images\"\xe8\x01\n" +
+	"\aEpisode\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12,\n" +
+	"\x04show\x18\x03 \x01(\v2\x18.spotifyinsights.v1.ShowR\x04show\x125\n" +
+	"\bduration\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12!\n" +
+	"\frelease_date\x18\x05 \x01(\tR\vreleaseDate\x121\n" +
+	"\x06images\x18\x06 \x03(\v2\x19.spotifyinsights.v1.ImageR\x06images\"7\n" +
+	"\x0fPlaybackContext\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03uri\x18\x02 \x01(\tR\x03uri\"\xf6\x02\n" +
+	"\x04Play\x12\x19\n" +
+	"\x05index\x18\x01 \x01(\x03H\x01R\x05index\x88\x01\x01\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x120\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x1c.spotifyinsights.v1.PlayTypeR\x04type\x127\n" +
+	"\tplayed_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bplayedAt\x12\x1b\n" +
+	"\tms_played\x18\x05 \x01(\x03R\bmsPlayed\x12=\n" +
+	"\acontext\x18\x06 \x01(\v2#.spotifyinsights.v1.PlaybackContextR\acontext\x121\n" +
+	"\x05track\x18\a \x01(\v2\x19.spotifyinsights.v1.TrackH\x00R\x05track\x127\n" +
+	"\aepisode\x18\b \x01(\v2\x1b.spotifyinsights.v1.EpisodeH\x00R\aepisodeB\x06\n" +
+	"\x04itemB\b\n" +
+	"\x06_index\"u\n" +
+	"\x14StreamHistoryRequest\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x03R\x05limit\x12/\n" +
+	"\x05order\x18\x03 \x01(\x0e2\x19.spotifyinsights.v1.OrderR\x05order\"\x83\x01\n" +
+	"\x0eGetTopsRequest\x127\n" +
+	"\x06entity\x18\x01 \x01(\x0e2\x1f.spotifyinsights.v1.ChartEntityR\x06entity\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\xd3\x01\n" +
+	"\aTopItem\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x121\n" +
+	"\x05track\x18\x03 \x01(\v2\x19.spotifyinsights.v1.TrackH\x00R\x05track\x124\n" +
+	"\x06artist\x18\x04 \x01(\v2\x1a.spotifyinsights.v1.ArtistH\x00R\x06artist\x121\n" +
+	"\x05album\x18\x05 \x01(\v2\x19.spotifyinsights.v1.AlbumH\x00R\x05albumB\x06\n" +
+	"\x04item\"D\n" +
+	"\x0fGetTopsResponse\x121\n" +
+	"\x05items\x18\x01 \x03(\v2\x1b.spotifyinsights.v1.TopItemR\x05items\"\x18\n" +
+	"\x16GetHourlyCountsRequest\"1\n" +
+	"\x17GetHourlyCountsResponse\x12\x16\n" +
+	"\x06counts\x18\x01 \x03(\x03R\x06counts\";\n" +
+	"\x15GetDailyCountsRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\"^\n" +
+	"\n" +
+	"DailyCount\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x14\n" +
+	"\x05start\x18\x03 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x04 \x01(\x03R\x03end\"L\n" +
+	"\x16GetDailyCountsResponse\x122\n" +
+	"\x04days\x18\x01 \x03(\v2\x1e.spotifyinsights.v1.DailyCountR\x04days\"\x16\n" +
+	"\x14GetNowPlayingRequest\"\xaa\x01\n" +
+	"\x15GetNowPlayingResponse\x12\x1d\n" +
+	"\n" +
+	"is_playing\x18\x01 \x01(\bR\tisPlaying\x121\n" +
+	"\x05track\x18\x02 \x01(\v2\x19.spotifyinsights.v1.TrackH\x00R\x05track\x127\n" +
+	"\aepisode\x18\x03 \x01(\v2\x1b.spotifyinsights.v1.EpisodeH\x00R\aepisodeB\x06\n" +
+	"\x04item\"\x13\n" +
+	"\x11WatchPlaysRequest*=\n" +
+	"\x05Order\x12\x15\n" +
+	"\x11ORDER_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tORDER_ASC\x10\x01\x12\x0e\n" +
+	"\n" +
+	"ORDER_DESC\x10\x02*w\n" +
+	"\vChartEntity\x12\x1c\n" +
+	"\x18CHART_ENTITY_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13CHART_ENTITY_TRACKS\x10\x01\x12\x18\n" +
+	"\x14CHART_ENTITY_ARTISTS\x10\x02\x12\x17\n" +
+	"\x13CHART_ENTITY_ALBUMS\x10\x03*Q\n" +
+	"\bPlayType\x12\x19\n" +
+	"\x15PLAY_TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fPLAY_TYPE_TRACK\x10\x01\x12\x15\n" +
+	"\x11PLAY_TYPE_EPISODE\x10\x022\xc8\x04\n" +
+	"\x0fInsightsService\x12U\n" +
+	"\rStreamHistory\x12(.spotifyinsights.v1.StreamHistoryRequest\x1a\x18.spotifyinsights.v1.Play0\x01\x12R\n" +
+	"\aGetTops\x12\".spotifyinsights.v1.GetTopsRequest\x1a#.spotifyinsights.v1.GetTopsResponse\x12j\n" +
+	"\x0fGetHourlyCounts\x12*.spotifyinsights.v1.GetHourlyCountsRequest\x1a+.spotifyinsights.v1.GetHourlyCountsResponse\x12g\n" +
+	"\x0eGetDailyCounts\x12).spotifyinsights.v1.GetDailyCountsRequest\x1a*.spotifyinsights.v1.GetDailyCountsResponse\x12d\n" +
+	"\rGetNowPlaying\x12(.spotifyinsights.v1.GetNowPlayingRequest\x1a).spotifyinsights.v1.GetNowPlayingResponse\x12O\n" +
+	"\n" +
+	"WatchPlays\x12%.spotifyinsights.v1.WatchPlaysRequest\x1a\x18.spotifyinsights.v1.Play0\x01B6Z4github.com/HenTaku321/spotify-insights-go/insightspbb\x06proto3"
+
+var (
+	file_spotify_insights_proto_rawDescOnce sync.Once
+	file_spotify_insights_proto_rawDescData []byte
+)
+
+func file_spotify_insights_proto_rawDescGZIP() []byte {
+	file_spotify_insights_proto_rawDescOnce.Do(func() {
+		file_spotify_insights_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_spotify_insights_proto_rawDesc), len(file_spotify_insights_proto_rawDesc)))
+	})
+	return file_spotify_insights_proto_rawDescData
+}
+
+var file_spotify_insights_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_spotify_insights_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_spotify_insights_proto_goTypes = []any{
+	(Order)(0),                      // 0: spotifyinsights.v1.Order
+	(ChartEntity)(0),                // 1: spotifyinsights.v1.ChartEntity
+	(PlayType)(0),                   // 2: spotifyinsights.v1.PlayType
+	(*Image)(nil),                   // 3: spotifyinsights.v1.Image
+	(*Artist)(nil),                  // 4: spotifyinsights.v1.Artist
+	(*Album)(nil),                   // 5: spotifyinsights.v1.Album
+	(*Track)(nil),                   // 6: spotifyinsights.v1.Track
+	(*Show)(nil),                    // 7: spotifyinsights.v1.Show
+	(*Episode)(nil),                 // 8: spotifyinsights.v1.Episode
+	(*PlaybackContext)(nil),         // 9: spotifyinsights.v1.PlaybackContext
+	(*Play)(nil),                    // 10: spotifyinsights.v1.Play
+	(*StreamHistoryRequest)(nil),    // 11: spotifyinsights.v1.StreamHistoryRequest
+	(*GetTopsRequest)(nil),          // 12: spotifyinsights.v1.GetTopsRequest
+	(*TopItem)(nil),                 // 13: spotifyinsights.v1.TopItem
+	(*GetTopsResponse)(nil),         // 14: spotifyinsights.v1.GetTopsResponse
+	(*GetHourlyCountsRequest)(nil),  // 15: spotifyinsights.v1.GetHourlyCountsRequest
+	(*GetHourlyCountsResponse)(nil), // 16: spotifyinsights.v1.GetHourlyCountsResponse
+	(*GetDailyCountsRequest)(nil),   // 17: spotifyinsights.v1.GetDailyCountsRequest
+	(*DailyCount)(nil),              // 18: spotifyinsights.v1.DailyCount
+	(*GetDailyCountsResponse)(nil),  // 19: spotifyinsights.v1.GetDailyCountsResponse
+	(*GetNowPlayingRequest)(nil),    // 20: spotifyinsights.v1.GetNowPlayingRequest
+	(*GetNowPlayingResponse)(nil),   // 21: spotifyinsights.v1.GetNowPlayingResponse
+	(*WatchPlaysRequest)(nil),       // 22: spotifyinsights.v1.WatchPlaysRequest
+	(*durationpb.Duration)(nil),     // 23: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),   // 24: google.protobuf.Timestamp
+}
+var file_spotify_insights_proto_depIdxs = []int32{
+	3,  // 0: spotifyinsights.v1.Artist.images:type_name -> spotifyinsights.v1.Image
+	4,  // 1: spotifyinsights.v1.Album.artists:type_name -> spotifyinsights.v1.Artist
+	3,  // 2: spotifyinsights.v1.Album.images:type_name -> spotifyinsights.v1.Image
+	5,  // 3: spotifyinsights.v1.Track.album:type_name -> spotifyinsights.v1.Album
+	4,  // 4: spotifyinsights.v1.Track.artists:type_name -> spotifyinsights.v1.Artist
+	23, // 5: spotifyinsights.v1.Track.duration:type_name -> google.protobuf.Duration
+	3,  // 6: spotifyinsights.v1.Show.images:type_name -> spotifyinsights.v1.Image
+	7,  // 7: spotifyinsights.v1.Episode.show:type_name -> spotifyinsights.v1.Show
+	23, // 8: spotifyinsights.v1.Episode.duration:type_name -> google.protobuf.Duration
+	3,  // 9: spotifyinsights.v1.Episode.images:type_name -> spotifyinsights.v1.Image
+	2,  // 10: spotifyinsights.v1.Play.type:type_name -> spotifyinsights.v1.PlayType
+	24, // 11: spotifyinsights.v1.Play.played_at:type_name -> google.protobuf.Timestamp
+	9,  // 12: spotifyinsights.v1.Play.context:type_name -> spotifyinsights.v1.PlaybackContext
+	6,  // 13: spotifyinsights.v1.Play.track:type_name -> spotifyinsights.v1.Track
+	8,  // 14: spotifyinsights.v1.Play.episode:type_name -> spotifyinsights.v1.Episode
+	0,  // 15: spotifyinsights.v1.StreamHistoryRequest.order:type_name -> spotifyinsights.v1.Order
+	1,  // 16: spotifyinsights.v1.GetTopsRequest.entity:type_name -> spotifyinsights.v1.ChartEntity
+	6,  // 17: spotifyinsights.v1.TopItem.track:type_name -> spotifyinsights.v1.Track
+	4,  // 18: spotifyinsights.v1.TopItem.artist:type_name -> spotifyinsights.v1.Artist
+	5,  // 19: spotifyinsights.v1.TopItem.album:type_name -> spotifyinsights.v1.Album
+	13, // 20: spotifyinsights.v1.GetTopsResponse.items:type_name -> spotifyinsights.v1.TopItem
+	18, // 21: spotifyinsights.v1.GetDailyCountsResponse.days:type_name -> spotifyinsights.v1.DailyCount
+	6,  // 22: spotifyinsights.v1.GetNowPlayingResponse.track:type_name -> spotifyinsights.v1.Track
+	8,  // 23: spotifyinsights.v1.GetNowPlayingResponse.episode:type_name -> spotifyinsights.v1.Episode
+	11, // 24: spotifyinsights.v1.InsightsService.StreamHistory:input_type -> spotifyinsights.v1.StreamHistoryRequest
+	12, // 25: spotifyinsights.v1.InsightsService.GetTops:input_type -> spotifyinsights.v1.GetTopsRequest
+	15, // 26: spotifyinsights.v1.InsightsService.GetHourlyCounts:input_type -> spotifyinsights.v1.GetHourlyCountsRequest
+	17, // 27: spotifyinsights.v1.InsightsService.GetDailyCounts:input_type -> spotifyinsights.v1.GetDailyCountsRequest
+	20, // 28: spotifyinsights.v1.InsightsService.GetNowPlaying:input_type -> spotifyinsights.v1.GetNowPlayingRequest
+	22, // 29: spotifyinsights.v1.InsightsService.WatchPlays:input_type -> spotifyinsights.v1.WatchPlaysRequest
+	10, // 30: spotifyinsights.v1.InsightsService.StreamHistory:output_type -> spotifyinsights.v1.Play
+	14, // 31: spotifyinsights.v1.InsightsService.GetTops:output_type -> spotifyinsights.v1.GetTopsResponse
+	16, // 32: spotifyinsights.v1.InsightsService.GetHourlyCounts:output_type -> spotifyinsights.v1.GetHourlyCountsResponse
+	19, // 33: spotifyinsights.v1.InsightsService.GetDailyCounts:output_type -> spotifyinsights.v1.GetDailyCountsResponse
+	21, // 34: spotifyinsights.v1.InsightsService.GetNowPlaying:output_type -> spotifyinsights.v1.GetNowPlayingResponse
+	10, // 35: spotifyinsights.v1.InsightsService.WatchPlays:output_type -> spotifyinsights.v1.Play
+	30, // [30:36] is the sub-list for method output_type
+	24, // [24:30] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
+}
+
+func init() { file_spotify_insights_proto_init() }
+func file_spotify_insights_proto_init() {
+	if File_spotify_insights_proto != nil {
+		return
+	}
+	file_spotify_insights_proto_msgTypes[7].OneofWrappers = []any{
+		(*Play_Track)(nil),
+		(*Play_Episode)(nil),
+	}
+	file_spotify_insights_proto_msgTypes[10].OneofWrappers = []any{
+		(*TopItem_Track)(nil),
+		(*TopItem_Artist)(nil),
+		(*TopItem_Album)(nil),
+	}
+	file_spotify_insights_proto_msgTypes[18].OneofWrappers = []any{
+		(*GetNowPlayingResponse_Track)(nil),
+		(*GetNowPlayingResponse_Episode)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_spotify_insights_proto_rawDesc), len(file_spotify_insights_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_spotify_insights_proto_goTypes,
+		DependencyIndexes: file_spotify_insights_proto_depIdxs,
+		EnumInfos:         file_spotify_insights_proto_enumTypes,
+		MessageInfos:      file_spotify_insights_proto_msgTypes,
+	}.Build()
+	File_spotify_insights_proto = out.File
+	file_spotify_insights_proto_goTypes = nil
+	file_spotify_insights_proto_depIdxs = nil
+}
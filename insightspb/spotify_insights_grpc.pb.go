@@ -0,0 +1,336 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: spotify_insights.proto
+
+package insightspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	InsightsService_StreamHistory_FullMethodName   = "/spotifyinsights.v1.InsightsService/StreamHistory"
+	InsightsService_GetTops_FullMethodName         = "/spotifyinsights.v1.InsightsService/GetTops"
+	InsightsService_GetHourlyCounts_FullMethodName = "/spotifyinsights.v1.InsightsService/GetHourlyCounts"
+	InsightsService_GetDailyCounts_FullMethodName  = "/spotifyinsights.v1.InsightsService/GetDailyCounts"
+	InsightsService_GetNowPlaying_FullMethodName   = "/spotifyinsights.v1.InsightsService/GetNowPlaying"
+	InsightsService_WatchPlays_FullMethodName      = "/spotifyinsights.v1.InsightsService/WatchPlays"
+)
+
+// InsightsServiceClient is the client API for InsightsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// InsightsService 提供播放记录 热门 收听量与正在播放, 只读取数据, 日期格式均为 2006-01-02
+// 参数错误返回 INVALID_ARGUMENT, 只读模式下需要请求 Spotify 时返回 FAILED_PRECONDITION
+type InsightsServiceClient interface {
+	// StreamHistory 按顺序流式返回播放记录, 包括单集
+	StreamHistory(ctx context.Context, in *StreamHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Play], error)
+	// GetTops 返回一段时间内(包括 from 和 to)按播放记录统计的热门曲目 艺术家或专辑
+	GetTops(ctx context.Context, in *GetTopsRequest, opts ...grpc.CallOption) (*GetTopsResponse, error)
+	// GetHourlyCounts 返回每个小时的收听量
+	GetHourlyCounts(ctx context.Context, in *GetHourlyCountsRequest, opts ...grpc.CallOption) (*GetHourlyCountsResponse, error)
+	// GetDailyCounts 返回一段时间内(包括 from 和 to)每日的收听量与播放记录范围, 没有播放记录的日期会被跳过
+	GetDailyCounts(ctx context.Context, in *GetDailyCountsRequest, opts ...grpc.CallOption) (*GetDailyCountsResponse, error)
+	// GetNowPlaying 返回正在播放的曲目或单集, 会请求 Spotify
+	GetNowPlaying(ctx context.Context, in *GetNowPlayingRequest, opts ...grpc.CallOption) (*GetNowPlayingResponse, error)
+	// WatchPlays 在 Run 保存新的播放记录时推送其中的曲目, 没有 index 与 context
+	WatchPlays(ctx context.Context, in *WatchPlaysRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Play], error)
+}
+
+type insightsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInsightsServiceClient(cc grpc.ClientConnInterface) InsightsServiceClient {
+	return &insightsServiceClient{cc}
+}
+
+func (c *insightsServiceClient) StreamHistory(ctx context.Context, in *StreamHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Play], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InsightsService_ServiceDesc.Streams[0], InsightsService_StreamHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamHistoryRequest, Play]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsightsService_StreamHistoryClient = grpc.ServerStreamingClient[Play]
+
+func (c *insightsServiceClient) GetTops(ctx context.Context, in *GetTopsRequest, opts ...grpc.CallOption) (*GetTopsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTopsResponse)
+	err := c.cc.Invoke(ctx, InsightsService_GetTops_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insightsServiceClient) GetHourlyCounts(ctx context.Context, in *GetHourlyCountsRequest, opts ...grpc.CallOption) (*GetHourlyCountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHourlyCountsResponse)
+	err := c.cc.Invoke(ctx, InsightsService_GetHourlyCounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insightsServiceClient) GetDailyCounts(ctx context.Context, in *GetDailyCountsRequest, opts ...grpc.CallOption) (*GetDailyCountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDailyCountsResponse)
+	err := c.cc.Invoke(ctx, InsightsService_GetDailyCounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insightsServiceClient) GetNowPlaying(ctx context.Context, in *GetNowPlayingRequest, opts ...grpc.CallOption) (*GetNowPlayingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetNowPlayingResponse)
+	err := c.cc.Invoke(ctx, InsightsService_GetNowPlaying_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insightsServiceClient) WatchPlays(ctx context.Context, in *WatchPlaysRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Play], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InsightsService_ServiceDesc.Streams[1], InsightsService_WatchPlays_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPlaysRequest, Play]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsightsService_WatchPlaysClient = grpc.ServerStreamingClient[Play]
+
+// InsightsServiceServer is the server API for InsightsService service.
+// All implementations must embed UnimplementedInsightsServiceServer
+// for forward compatibility.
+//
+// InsightsService 提供播放记录 热门 收听量与正在播放, 只读取数据, 日期格式均为 2006-01-02
+// 参数错误返回 INVALID_ARGUMENT, 只读模式下需要请求 Spotify 时返回 FAILED_PRECONDITION
+type InsightsServiceServer interface {
+	// StreamHistory 按顺序流式返回播放记录, 包括单集
+	StreamHistory(*StreamHistoryRequest, grpc.ServerStreamingServer[Play]) error
+	// GetTops 返回一段时间内(包括 from 和 to)按播放记录统计的热门曲目 艺术家或专辑
+	GetTops(context.Context, *GetTopsRequest) (*GetTopsResponse, error)
+	// GetHourlyCounts 返回每个小时的收听量
+	GetHourlyCounts(context.Context, *GetHourlyCountsRequest) (*GetHourlyCountsResponse, error)
+	// GetDailyCounts 返回一段时间内(包括 from 和 to)每日的收听量与播放记录范围, 没有播放记录的日期会被跳过
+	GetDailyCounts(context.Context, *GetDailyCountsRequest) (*GetDailyCountsResponse, error)
+	// GetNowPlaying 返回正在播放的曲目或单集, 会请求 Spotify
+	GetNowPlaying(context.Context, *GetNowPlayingRequest) (*GetNowPlayingResponse, error)
+	// WatchPlays 在 Run 保存新的播放记录时推送其中的曲目, 没有 index 与 context
+	WatchPlays(*WatchPlaysRequest, grpc.ServerStreamingServer[Play]) error
+	mustEmbedUnimplementedInsightsServiceServer()
+}
+
+// UnimplementedInsightsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInsightsServiceServer struct{}
+
+func (UnimplementedInsightsServiceServer) StreamHistory(*StreamHistoryRequest, grpc.ServerStreamingServer[Play]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamHistory not implemented")
+}
+func (UnimplementedInsightsServiceServer) GetTops(context.Context, *GetTopsRequest) (*GetTopsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTops not implemented")
+}
+func (UnimplementedInsightsServiceServer) GetHourlyCounts(context.Context, *GetHourlyCountsRequest) (*GetHourlyCountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHourlyCounts not implemented")
+}
+func (UnimplementedInsightsServiceServer) GetDailyCounts(context.Context, *GetDailyCountsRequest) (*GetDailyCountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDailyCounts not implemented")
+}
+func (UnimplementedInsightsServiceServer) GetNowPlaying(context.Context, *GetNowPlayingRequest) (*GetNowPlayingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNowPlaying not implemented")
+}
+func (UnimplementedInsightsServiceServer) WatchPlays(*WatchPlaysRequest, grpc.ServerStreamingServer[Play]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPlays not implemented")
+}
+func (UnimplementedInsightsServiceServer) mustEmbedUnimplementedInsightsServiceServer() {}
+func (UnimplementedInsightsServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeInsightsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InsightsServiceServer will
+// result in compilation errors.
+type UnsafeInsightsServiceServer interface {
+	mustEmbedUnimplementedInsightsServiceServer()
+}
+
+func RegisterInsightsServiceServer(s grpc.ServiceRegistrar, srv InsightsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedInsightsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InsightsService_ServiceDesc, srv)
+}
+
+func _InsightsService_StreamHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InsightsServiceServer).StreamHistory(m, &grpc.GenericServerStream[StreamHistoryRequest, Play]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsightsService_StreamHistoryServer = grpc.ServerStreamingServer[Play]
+
+func _InsightsService_GetTops_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsightsServiceServer).GetTops(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsightsService_GetTops_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsightsServiceServer).GetTops(ctx, req.(*GetTopsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsightsService_GetHourlyCounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHourlyCountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsightsServiceServer).GetHourlyCounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsightsService_GetHourlyCounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsightsServiceServer).GetHourlyCounts(ctx, req.(*GetHourlyCountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsightsService_GetDailyCounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDailyCountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsightsServiceServer).GetDailyCounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsightsService_GetDailyCounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsightsServiceServer).GetDailyCounts(ctx, req.(*GetDailyCountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsightsService_GetNowPlaying_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNowPlayingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsightsServiceServer).GetNowPlaying(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsightsService_GetNowPlaying_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsightsServiceServer).GetNowPlaying(ctx, req.(*GetNowPlayingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsightsService_WatchPlays_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPlaysRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InsightsServiceServer).WatchPlays(m, &grpc.GenericServerStream[WatchPlaysRequest, Play]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsightsService_WatchPlaysServer = grpc.ServerStreamingServer[Play]
+
+// InsightsService_ServiceDesc is the grpc.ServiceDesc for InsightsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InsightsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "spotifyinsights.v1.InsightsService",
+	HandlerType: (*InsightsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTops",
+			Handler:    _InsightsService_GetTops_Handler,
+		},
+		{
+			MethodName: "GetHourlyCounts",
+			Handler:    _InsightsService_GetHourlyCounts_Handler,
+		},
+		{
+			MethodName: "GetDailyCounts",
+			Handler:    _InsightsService_GetDailyCounts_Handler,
+		},
+		{
+			MethodName: "GetNowPlaying",
+			Handler:    _InsightsService_GetNowPlaying_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamHistory",
+			Handler:       _InsightsService_StreamHistory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchPlays",
+			Handler:       _InsightsService_WatchPlays_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "spotify_insights.proto",
+}
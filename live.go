@@ -0,0 +1,240 @@
+package spotify
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LiveEvent 的 Type 字段, 也是 SSE 的 event 字段
+const (
+	EventTrackChanged = "track-changed" // 开始播放或切换了曲目, NowPlaying 为正在播放的曲目或单集
+	EventPaused       = "paused"        // 暂停或停止播放
+	EventNewPlay      = "new-play"      // 保存了新的播放记录, Plays 为新增的曲目
+)
+
+// liveHeartbeatInterval 是 SSE 注释行的发送间隔, 避免连接被代理断开
+const liveHeartbeatInterval = time.Second * 15
+
+type LiveEvent struct {
+	Type       string            `json:"type"`
+	Time       string            `json:"time"`
+	NowPlaying *CurrentlyPlaying `json:"now_playing,omitempty"`
+	Plays      []PlayedTrack     `json:"plays,omitempty"`
+}
+
+// liveHub 将事件广播给所有订阅者, 并记录最近一次的播放状态用于新订阅者的初始化
+type liveHub struct {
+	mu          sync.Mutex
+	subscribers map[chan LiveEvent]struct{}
+	nowPlaying  *CurrentlyPlaying
+	polledAt    time.Time // 最近一次轮询成功的时间, 没有订阅者时为零值
+}
+
+// live 返回 Client 的 liveHub, 第一次调用时创建
+func (c *Client) live() *liveHub {
+	c.liveOnce.Do(func() {
+		c.liveHub = &liveHub{subscribers: map[chan LiveEvent]struct{}{}}
+	})
+	return c.liveHub
+}
+
+func (h *liveHub) subscribe() chan LiveEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan LiveEvent, 16)
+	h.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (h *liveHub) unsubscribe(ch chan LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers, ch)
+}
+
+func (h *liveHub) subscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+// publish 广播事件, 不会阻塞, 缓冲区已满的订阅者会错过这个事件
+func (h *liveHub) publish(event LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.Time == "" {
+		event.Time = time.Now().Format(time.DateTime)
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Debug("订阅者处理过慢, 已丢弃事件", "事件", event.Type)
+		}
+	}
+}
+
+// updateNowPlaying 与上一次的播放状态比较, 返回需要广播的事件, 没有变化时返回 nil
+func (h *liveHub) updateNowPlaying(cp *CurrentlyPlaying) *LiveEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous := h.nowPlaying
+	h.nowPlaying = cp
+	h.polledAt = time.Now()
+
+	switch {
+	case cp == nil && previous == nil:
+		return nil
+	case cp == nil:
+		return &LiveEvent{Type: EventPaused}
+	case previous == nil || nowPlayingID(previous) != nowPlayingID(cp):
+		return &LiveEvent{Type: EventTrackChanged, NowPlaying: cp}
+	}
+
+	return nil
+}
+
+// resetNowPlaying 在没有订阅者时调用, 清除记录的播放状态
+func (h *liveHub) resetNowPlaying() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nowPlaying = nil
+	h.polledAt = time.Time{}
+}
+
+// recentNowPlaying 返回轮询得到的播放状态, 轮询未运行或结果早于 maxAge 时 ok 为 false
+func (h *liveHub) recentNowPlaying(maxAge time.Duration) (cp *CurrentlyPlaying, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.polledAt.IsZero() || time.Since(h.polledAt) > maxAge {
+		return nil, false
+	}
+
+	return h.nowPlaying, true
+}
+
+func nowPlayingID(cp *CurrentlyPlaying) string {
+	if cp.Episode != nil {
+		return cp.Episode.ID
+	}
+	return cp.ID
+}
+
+// RunLivePoller 定时获取正在播放的曲目, 播放状态变化时向 /events 的订阅者广播, 没有订阅者时不会请求 Spotify
+// 无论有多少订阅者都只有这一个轮询, 应与 Run 同时运行
+func (c *Client) RunLivePoller(dbc dbClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	hub := c.live()
+
+	for ; ; <-ticker.C {
+		if hub.subscriberCount() == 0 {
+			// 重新有订阅者时视为从未播放, 以便广播当前的曲目
+			hub.resetNowPlaying()
+			continue
+		}
+
+		cp, err := c.GetCurrentlyPlayingTrack(dbc)
+		if err != nil {
+			slog.Warn("Spotify 获取正在播放的曲目失败", "error", err)
+			continue
+		}
+
+		event := hub.updateNowPlaying(cp)
+		if event != nil {
+			hub.publish(*event)
+		}
+	}
+}
+
+// publishNewPlays 在保存最近播放后调用
+func (c *Client) publishNewPlays(plays []PlayedTrack) {
+	if len(plays) == 0 {
+		return
+	}
+
+	c.live().publish(LiveEvent{Type: EventNewPlay, Plays: plays})
+}
+
+// serveEvents 以 Server-Sent Events 推送 LiveEvent, 连接后会先推送一次当前的播放状态(如果正在播放)
+func (c *Client) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeAPIError(w, &apiError{http.StatusMethodNotAllowed, APIErrorMethodNotAllowed, "只接受 GET 请求"})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	hub := c.live()
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	hub.mu.Lock()
+	cp := hub.nowPlaying
+	hub.mu.Unlock()
+
+	if cp != nil {
+		err := writeLiveEvent(w, LiveEvent{Type: EventTrackChanged, Time: time.Now().Format(time.DateTime), NowPlaying: cp})
+		if err != nil {
+			return
+		}
+	}
+
+	err := rc.Flush()
+	if err != nil {
+		slog.Warn("响应不支持 SSE", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = w.Write([]byte(": heartbeat\n\n"))
+		case event := <-ch:
+			err = writeLiveEvent(w, event)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			slog.Debug("SSE 连接已断开", "error", err)
+			return
+		}
+	}
+}
+
+func writeLiveEvent(w http.ResponseWriter, event LiveEvent) error {
+	j, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("event: " + event.Type + "\ndata: " + string(j) + "\n\n"))
+	return err
+}
@@ -0,0 +1,109 @@
+package spotify
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// 本地文件没有 Spotify ID, 以名称生成的 ID 代替, 长度与 Spotify ID 相同, 且含有 Spotify ID 不会出现的冒号
+const localIDPrefix = "local:"
+
+func isLocalTrack(track *spotify.SimpleTrack) bool {
+	return track.ID == "" || strings.HasPrefix(string(track.URI), "spotify:local:")
+}
+
+func isLocalID(id string) bool {
+	return strings.HasPrefix(id, localIDPrefix)
+}
+
+// localID 以名称生成稳定的 ID, parts 需区分类型, 如 "track" "artist" "album"
+func localID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(strings.Join(parts, "\x00"))))
+	return localIDPrefix + hex.EncodeToString(sum[:8])
+}
+
+// saveLocalTrack 存储本地文件的信息并返回生成的 ID, 本地文件最多只有一位艺术家
+func saveLocalTrack(dbc dbClient, track *spotify.SimpleTrack) (string, error) {
+	var artistNames []string
+
+	for _, artist := range track.Artists {
+		artistNames = append(artistNames, artist.Name)
+	}
+
+	firstArtist := ""
+	if len(artistNames) > 0 {
+		firstArtist = artistNames[0]
+	}
+
+	trackID := localID("track", track.Name, firstArtist, track.Album.Name)
+
+	err := saveTrackByNames(dbc, trackID, track.Name, artistNames, track.Album.Name, int(track.Duration))
+	if err != nil {
+		return "", err
+	}
+
+	return trackID, nil
+}
+
+// saveTrackByNames 只以名称存储曲目信息, 艺术家与专辑使用生成的 ID, 用于本地文件与无法从 Spotify 获取信息的曲目
+func saveTrackByNames(dbc dbClient, trackID, name string, artistNames []string, albumName string, durationMs int) error {
+	var artistIDs []string
+
+	for _, artistName := range artistNames {
+		artistID := localID("artist", artistName)
+
+		err := saveID(dbc, artistID, &ArtistMap{Name: artistName})
+		if err != nil {
+			return err
+		}
+
+		artistIDs = append(artistIDs, artistID)
+	}
+
+	firstArtist := ""
+	if len(artistNames) > 0 {
+		firstArtist = artistNames[0]
+	}
+
+	albumID := localID("album", albumName, firstArtist)
+
+	err := saveID(dbc, albumID, &AlbumMap{Name: albumName, ArtistsIDs: artistIDs})
+	if err != nil {
+		return err
+	}
+
+	return saveID(dbc, trackID, &TrackMap{
+		AlbumID:    albumID,
+		ArtistsIDs: artistIDs,
+		Duration:   time.UnixMilli(int64(durationMs)).UTC().Format(time.TimeOnly),
+		Name:       name,
+	})
+}
+
+// getCanonicalTrackID 返回经过 Spotify 曲目重链接(Track Relinking)后应使用的 ID, 即第一次获取信息时使用的 ID
+// 数据库字典 linked-track-ids 中以重链接后的 ID 为键, 原 ID 为值
+func getCanonicalTrackID(dbc dbClient, id string) (string, error) {
+	canonicalID, err := dbc.GetMapStr("linked-track-ids", id)
+	if err != nil {
+		return "", err
+	}
+
+	if canonicalID == "" {
+		return id, nil
+	}
+
+	return canonicalID, nil
+}
+
+// saveLinkedTrackID 记录重链接, 之后 linkedID 会被视为 id
+func saveLinkedTrackID(dbc dbClient, id, linkedID string) error {
+	if id == linkedID {
+		return nil
+	}
+
+	return dbc.SetMap("linked-track-ids", linkedID, id)
+}
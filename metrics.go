@@ -0,0 +1,251 @@
+package spotify
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "spotify_insights"
+
+// 定时任务与 Spotify 请求的指标为全局指标, 在 MetricsHandler 中注册
+var (
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of collector jobs, including failed attempts.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"job"})
+
+	jobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_failures_total",
+		Help:      "Number of failed collector job attempts.",
+	}, []string{"job"})
+
+	jobRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_retries_total",
+		Help:      "Number of collector job retries after a failure.",
+	}, []string{"job"})
+
+	spotifyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "spotify_api_requests_total",
+		Help:      "Number of Spotify Web API requests by endpoint and status code.",
+	}, []string{"endpoint", "status"})
+
+	spotifyRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "spotify_api_request_duration_seconds",
+		Help:      "Duration of Spotify Web API requests by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of metadata cache lookups by type and result (hit or miss).",
+	}, []string{"type", "result"})
+)
+
+// observeJob 记录一次定时任务的运行, start 为开始时间
+func observeJob(job string, start time.Time, err error) {
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		jobFailures.WithLabelValues(job).Inc()
+	}
+}
+
+// observeCache 记录一次 get*Cache 的查询, typ 为 "artist" 等
+func observeCache(typ string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	cacheRequests.WithLabelValues(typ, result).Inc()
+}
+
+// spotifyIDRegexp 匹配路径中的 Spotify ID, 用于合并同一接口的不同请求
+var spotifyIDRegexp = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// spotifyEndpoint 返回用于指标的接口名称, 如 /v1/tracks/{id}
+func spotifyEndpoint(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+
+	for i, segment := range segments {
+		if spotifyIDRegexp.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return r.Method + " " + strings.Join(segments, "/")
+}
+
+// metricsTransport 记录 Spotify 请求的数量与耗时
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	endpoint := spotifyEndpoint(r)
+	start := time.Now()
+
+	resp, err := t.base.RoundTrip(r)
+
+	spotifyRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	spotifyRequests.WithLabelValues(endpoint, status).Inc()
+
+	return resp, err
+}
+
+// instrumentHTTPClient 为请求 Spotify 的 http.Client 添加指标与 span
+func instrumentHTTPClient(httpClient *http.Client) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	httpClient.Transport = &metricsTransport{&tracingTransport{base}}
+
+	return httpClient
+}
+
+// metricsNowPlayingTTL 是正在播放指标的最长缓存时间, 避免每次抓取都请求 Spotify
+const metricsNowPlayingTTL = time.Minute
+
+// listeningCollector 在每次抓取时从数据库读取收听相关的指标
+// 正在播放的指标优先使用 RunLivePoller 的轮询结果, 否则请求 Spotify 并缓存 metricsNowPlayingTTL
+type listeningCollector struct {
+	c   *Client
+	dbc dbClient
+
+	nowPlayingMu sync.Mutex
+	nowPlaying   *CurrentlyPlaying
+	nowPlayingAt time.Time
+
+	historyEntries *prometheus.Desc
+	playsToday     *prometheus.Desc
+	playing        *prometheus.Desc
+	collectorError *prometheus.Desc
+}
+
+func newListeningCollector(c *Client, dbc dbClient) *listeningCollector {
+	return &listeningCollector{
+		c:              c,
+		dbc:            dbc,
+		historyEntries: prometheus.NewDesc(metricsNamespace+"_playback_history_entries", "Number of entries in the playback history.", nil, nil),
+		playsToday:     prometheus.NewDesc(metricsNamespace+"_plays_today", "Number of plays saved today.", nil, nil),
+		playing:        prometheus.NewDesc(metricsNamespace+"_currently_playing", "Whether something is playing right now (1) or not (0), by item type.", []string{"type"}, nil),
+		collectorError: prometheus.NewDesc(metricsNamespace+"_listening_scrape_error", "Whether reading the listening metrics failed (1) or not (0), by metric.", []string{"metric"}, nil),
+	}
+}
+
+func (lc *listeningCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lc.historyEntries
+	ch <- lc.playsToday
+	ch <- lc.playing
+	ch <- lc.collectorError
+}
+
+func (lc *listeningCollector) Collect(ch chan<- prometheus.Metric) {
+	scrapeError := func(metric string, err error) {
+		v := 0.0
+		if err != nil {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(lc.collectorError, prometheus.GaugeValue, v, metric)
+	}
+
+	total, err := lc.c.GetTotalPlaybackHistoryCount(lc.dbc)
+	if err == nil {
+		ch <- prometheus.MustNewConstMetric(lc.historyEntries, prometheus.GaugeValue, float64(total))
+	}
+	scrapeError("playback_history_entries", err)
+
+	today, err := lc.c.GetPlaybackRangeOnADay(lc.dbc, time.Now())
+	if err == nil {
+		count := 0
+		if today != nil {
+			count = today.End - today.Start + 1
+		}
+		ch <- prometheus.MustNewConstMetric(lc.playsToday, prometheus.GaugeValue, float64(count))
+	}
+	scrapeError("plays_today", err)
+
+	if lc.c.C == nil {
+		return
+	}
+
+	cp, err := lc.currentlyPlaying()
+	if err == nil {
+		for _, typ := range []string{EntryTypeTrack, EntryTypeEpisode} {
+			v := 0.0
+			if cp != nil && cp.Type == typ {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(lc.playing, prometheus.GaugeValue, v, typ)
+		}
+	}
+	scrapeError("currently_playing", err)
+}
+
+// currentlyPlaying 返回正在播放的曲目, 轮询结果与缓存都过期时才请求 Spotify, 请求失败时不缓存
+func (lc *listeningCollector) currentlyPlaying() (*CurrentlyPlaying, error) {
+	cp, ok := lc.c.live().recentNowPlaying(metricsNowPlayingTTL)
+	if ok {
+		return cp, nil
+	}
+
+	lc.nowPlayingMu.Lock()
+	defer lc.nowPlayingMu.Unlock()
+
+	if !lc.nowPlayingAt.IsZero() && time.Since(lc.nowPlayingAt) <= metricsNowPlayingTTL {
+		return lc.nowPlaying, nil
+	}
+
+	cp, err := lc.c.GetCurrentlyPlayingTrack(lc.dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.nowPlaying = cp
+	lc.nowPlayingAt = time.Now()
+
+	return cp, nil
+}
+
+// MetricsHandler 返回 Prometheus 的 /metrics 接口, 包括定时任务 Spotify 请求 缓存命中与收听相关的指标
+// 正在播放的指标最多缓存 metricsNowPlayingTTL
+func (c *Client) MetricsHandler(dbc dbClient) http.Handler {
+	registry := prometheus.NewRegistry()
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		jobDuration,
+		jobFailures,
+		jobRetries,
+		spotifyRequests,
+		spotifyRequestDuration,
+		cacheRequests,
+		newListeningCollector(c, dbc),
+	)
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
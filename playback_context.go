@@ -0,0 +1,157 @@
+package spotify
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	ContextTypeAlbum      = "album"
+	ContextTypeArtist     = "artist"
+	ContextTypePlaylist   = "playlist"
+	ContextTypeCollection = "collection" // 已点赞的歌曲
+	ContextTypeNone       = "none"       // 单曲播放, 搜索结果等没有上下文的播放
+)
+
+// PlaybackContext 是播放时所处的上下文, 即从哪个歌单 专辑 艺术家(电台)开始播放
+type PlaybackContext struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+}
+
+func convertPlaybackContext(pc spotify.PlaybackContext) *PlaybackContext {
+	if pc.URI == "" {
+		return nil
+	}
+
+	return &PlaybackContext{Type: pc.Type, URI: string(pc.URI)}
+}
+
+// ID 返回上下文 URI 中的 ID 部分, 已点赞的歌曲没有 ID
+func (pc *PlaybackContext) ID() string {
+	if pc == nil {
+		return ""
+	}
+
+	return pc.URI[strings.LastIndex(pc.URI, ":")+1:]
+}
+
+// getContextType 返回播放记录的上下文类型, 已点赞的歌曲的 URI 形如 spotify:user:xxx:collection
+func getContextType(pc *PlaybackContext) string {
+	if pc == nil {
+		return ContextTypeNone
+	}
+
+	if strings.HasSuffix(pc.URI, ":collection") {
+		return ContextTypeCollection
+	}
+
+	return pc.Type
+}
+
+// GetPlaybackEntries 返回数据库中的原始播放记录, 包括单集, 不请求 Spotify
+func (c *Client) GetPlaybackEntries(dbc dbClient, start, stop int64) ([]PlaybackEntry, error) {
+	return getPlaybackEntries(dbc, start, stop)
+}
+
+// getPlaybackEntries 返回播放记录的原始存储格式
+func getPlaybackEntries(dbc dbClient, start, stop int64) ([]PlaybackEntry, error) {
+	playbackHistory, err := dbc.GetSlice("playback-history", start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PlaybackEntry
+
+	for _, entry := range playbackHistory {
+		pe := PlaybackEntry{}
+
+		err = json.Unmarshal([]byte(entry), &pe)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, pe)
+	}
+
+	return entries, nil
+}
+
+// getPlaybackEntriesDuringATime 返回一段时间内的播放记录(包括t1和t2), 若其中一个日期没有数据会返回nil
+func (c *Client) getPlaybackEntriesDuringATime(dbc dbClient, t1, t2 time.Time) ([]PlaybackEntry, error) {
+	rangeFromT1ToT2, err := c.GetPlaybackRangeDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if rangeFromT1ToT2 == nil {
+		return nil, nil
+	}
+
+	return getPlaybackEntries(dbc, int64(rangeFromT1ToT2.Start), int64(rangeFromT1ToT2.End))
+}
+
+// GetPlaybackContextTypeCounts 返回一段时间内各上下文类型的收听量(包括t1和t2), 键为 ContextType*, 若其中一个日期没有数据会返回nil
+func (c *Client) GetPlaybackContextTypeCounts(dbc dbClient, t1, t2 time.Time) (map[string]int, error) {
+	entries, err := c.getPlaybackEntriesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		return nil, nil
+	}
+
+	counts := map[string]int{}
+
+	for _, entry := range entries {
+		counts[getContextType(entry.Context)]++
+	}
+
+	return counts, nil
+}
+
+// GetTopContextsURIs 返回一段时间内最常播放的上下文 URI(包括t1和t2), contextType 为空则不区分类型, 若其中一个日期没有数据会返回nil, limit为0则不限制
+func (c *Client) GetTopContextsURIs(dbc dbClient, t1, t2 time.Time, contextType string, limit int) ([]Tops, error) {
+	entries, err := c.getPlaybackEntriesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		return nil, nil
+	}
+
+	contextCounts := map[string]int{}
+
+	for _, entry := range entries {
+		if entry.Context == nil {
+			continue
+		}
+
+		if contextType != "" && getContextType(entry.Context) != contextType {
+			continue
+		}
+
+		contextCounts[entry.Context.URI]++
+	}
+
+	return sortTops(contextCounts, limit), nil
+}
+
+// GetTopPlaylistsIDs 返回一段时间内最常播放的歌单ID(包括t1和t2), 若其中一个日期没有数据会返回nil, limit为0则不限制
+func (c *Client) GetTopPlaylistsIDs(dbc dbClient, t1, t2 time.Time, limit int) ([]Tops, error) {
+	tops, err := c.GetTopContextsURIs(dbc, t1, t2, ContextTypePlaylist, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tops {
+		tops[i].ID = (&PlaybackContext{URI: tops[i].ID}).ID()
+	}
+
+	return tops, nil
+}
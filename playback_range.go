@@ -25,10 +25,41 @@ func getTime(layout, timeStr string) (time.Time, error) {
 		return time.Time{}, nil
 	}
 
+	playedAt, err := getPlayedAt(timeStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
 	if layout == time.DateTime {
-		return time.Parse(layout, timeStr[44:63])
+		return time.Parse(layout, playedAt)
+	}
+	return time.Parse(layout, playedAt[:10])
+}
+
+// getPlayedAt 返回播放记录中 time.DateTime 格式的播放时间, entry 为空会返回空字符串
+func getPlayedAt(entry string) (string, error) {
+	if entry == "" {
+		return "", nil
+	}
+
+	pe := PlaybackEntry{}
+
+	err := json.Unmarshal([]byte(entry), &pe)
+	if err != nil {
+		return "", err
+	}
+
+	return pe.PlayedAt, nil
+}
+
+// getPlayedDate 返回播放记录中 time.DateOnly 格式的播放日期, entry 为空会返回空字符串
+func getPlayedDate(entry string) (string, error) {
+	playedAt, err := getPlayedAt(entry)
+	if err != nil || playedAt == "" {
+		return "", err
 	}
-	return time.Parse(layout, timeStr[44:54])
+
+	return playedAt[:10], nil
 }
 
 // getTotalPlayedCountInAType 获取一段时间内一个类型的收听量, 若其中一个日期没有数据会返回 nil
@@ -100,68 +131,91 @@ func (c *Client) savePlaybackRangeOnADay(dbc dbClient, date time.Time, count int
 		return err
 	}
 
-	rangeTodayStartTimeStr := rangeTodayStart[44:54]
-	rangeTodayEndTimeStr := rangeTodayEnd[44:54]
-	rangeTodayStartMinusOneTimeStr := rangeTodayStartMinusOne[44:54]
-	lastPlayedTimeStr := lastPlayed[44:54]
+	rangeTodayStartTimeStr, err := getPlayedDate(rangeTodayStart)
+	if err != nil {
+		return err
+	}
+
+	rangeTodayEndTimeStr, err := getPlayedDate(rangeTodayEnd)
+	if err != nil {
+		return err
+	}
+
+	rangeTodayStartMinusOneTimeStr, err := getPlayedDate(rangeTodayStartMinusOne)
+	if err != nil {
+		return err
+	}
+
+	lastPlayedTimeStr, err := getPlayedDate(lastPlayed)
+	if err != nil {
+		return err
+	}
 
 	if rangeTodayStartTimeStr != dateDateOnly || rangeTodayEndTimeStr != dateDateOnly || rangeTodayStartMinusOne != "" && rangeTodayStartMinusOneTimeStr == dateDateOnly && rangeToday.Start > 0 || dateDateOnly == lastPlayedTimeStr && rangeTodayEndPlusOne != "" {
 		slog.Warn("每日播放量统计不匹配, 可能是运行时退出导致, 正在修复")
 		defer slog.Info("每日播放量统计修复完成")
-		err = dbc.Delete("daily-playback-ranges")
+		err = rebuildDailyPlaybackRanges(dbc)
 		if err != nil {
 			return err
 		}
 
-		playbackRanges := map[time.Time]*PlaybackRange{}
+		c.emitWebhook(dbc, WebhookRepairPerformed, map[string]string{"kind": RepairDailyPlaybackRanges})
 
-		for i := 0; ; i += 50 {
-			playbackHistory, err := dbc.GetSlice("playback-history", int64(i), int64(i+49))
-			if err != nil {
-				return err
-			}
+		return errDailyPlaybackRangesNotMatch
+	}
 
-			if len(playbackHistory) == 0 {
-				break
-			}
+	return nil
+}
 
-			for _, playback := range playbackHistory {
-				playbackTime, err := getTime(time.DateOnly, playback)
-				if err != nil {
-					return err
-				}
-
-				// 第一次统计这个日期
-				if playbackRanges[playbackTime] == nil {
-					playbackRanges[playbackTime] = &PlaybackRange{}
-					if playbackRanges[playbackTime.AddDate(0, 0, -1)] != nil {
-						playbackRanges[playbackTime].Start = playbackRanges[playbackTime.AddDate(0, 0, -1)].End + 1
-						playbackRanges[playbackTime].End = playbackRanges[playbackTime].Start
-					}
-				} else {
-					playbackRanges[playbackTime].End++
-				}
-			}
+// rebuildDailyPlaybackRanges 按完整的播放记录重新统计每日收听量
+func rebuildDailyPlaybackRanges(dbc dbClient) error {
+	err := dbc.Delete("daily-playback-ranges")
+	if err != nil {
+		return err
+	}
 
-			// 到尾了
-			if len(playbackHistory) != 50 {
-				break
-			}
+	playbackRanges := map[string]*PlaybackRange{}
+
+	for i := 0; ; i += 50 {
+		playbackHistory, err := dbc.GetSlice("playback-history", int64(i), int64(i+49))
+		if err != nil {
+			return err
+		}
+
+		if len(playbackHistory) == 0 {
+			break
 		}
 
-		for day, pr := range playbackRanges {
-			j, err = json.Marshal(pr)
+		for j, playback := range playbackHistory {
+			playbackDate, err := getPlayedDate(playback)
 			if err != nil {
 				return err
 			}
 
-			err = dbc.SetMap("daily-playback-ranges", day.Format(time.DateOnly), string(j))
-			if err != nil {
-				return err
+			// 第一次统计这个日期, 前一天可能没有收听
+			if playbackRanges[playbackDate] == nil {
+				playbackRanges[playbackDate] = &PlaybackRange{Start: i + j, End: i + j}
+			} else {
+				playbackRanges[playbackDate].End = i + j
 			}
 		}
 
-		return errDailyPlaybackRangesNotMatch
+		// 到尾了
+		if len(playbackHistory) != 50 {
+			break
+		}
+	}
+
+	for day, pr := range playbackRanges {
+		j, err := json.Marshal(pr)
+		if err != nil {
+			return err
+		}
+
+		err = dbc.SetMap("daily-playback-ranges", day, string(j))
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -211,7 +265,7 @@ func (c *Client) GetPlaybackRangeDuringATime(dbc dbClient, t1, t2 time.Time) (*P
 	return &PlaybackRange{start.Start, end.End}, nil
 }
 
-// savePlaybackCount 存储歌曲和专辑和艺术家的收听量
+// savePlaybackCount 存储曲目和歌曲(所有版本)和专辑和艺术家的收听量
 func (c *Client) savePlaybackCounts(dbc dbClient, tracks []PlayedTrack) error {
 	for _, track := range tracks {
 		counts, err := dbc.GetMapInt64("track-playback-counts", track.ID)
@@ -224,6 +278,11 @@ func (c *Client) savePlaybackCounts(dbc dbClient, tracks []PlayedTrack) error {
 			return err
 		}
 
+		err = c.saveSongPlaybackCount(dbc, track)
+		if err != nil {
+			return err
+		}
+
 		counts, err = dbc.GetMapInt64("album-playback-counts", track.Album.ID)
 		if err != nil {
 			return err
@@ -380,3 +439,66 @@ func (c *Client) GetHourlyPlayBackCounts(dbc dbClient) (map[int]int, error) {
 
 	return res, nil
 }
+
+// RebuildAggregates 按完整的播放记录重新统计每日 每小时收听量与曲目 歌曲 专辑 艺术家的收听量
+// 用于导入或修改了播放记录之后, 运行期间不应同时保存最近播放
+func (c *Client) RebuildAggregates(dbc dbClient) error {
+	slog.Info("开始重新统计收听量, 请勿在结束前退出程序")
+	defer slog.Info("重新统计收听量结束")
+
+	err := rebuildDailyPlaybackRanges(dbc)
+	if err != nil {
+		return err
+	}
+
+	err = dbc.Delete("hourly-playback-counts")
+	if err != nil {
+		return err
+	}
+
+	err = dbc.SetMap("updated-times", "last-saved-hourly-playback-time", "")
+	if err != nil {
+		return err
+	}
+
+	err = c.saveHourlyPlaybackCounts(dbc)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{"track-playback-counts", "song-playback-counts", "album-playback-counts", "artist-playback-counts"} {
+		err = dbc.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	total, err := c.GetTotalPlaybackHistoryCount(dbc)
+	if err != nil {
+		return err
+	}
+
+	for i := int64(0); i < total; i += 50 {
+		ph, err := c.GetPlaybackHistory(dbc, i, i+49)
+		if err != nil {
+			return err
+		}
+
+		err = c.savePlaybackCounts(dbc, ph)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 导入的播放记录可能早于已存储的排行榜, 下一次 saveCharts 会从第一次播放开始补充缺少的周期, 已存储的不会被覆盖
+	for _, period := range chartPeriods {
+		err = dbc.SetMap("updated-times", period+"-charts", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	c.emitWebhook(dbc, WebhookRepairPerformed, map[string]string{"kind": RepairAggregates})
+
+	return nil
+}
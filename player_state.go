@@ -0,0 +1,230 @@
+package spotify
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+const DeviceTypeUnknown = "Unknown"
+
+// PlayerState 是采样到的播放器状态, 以曲目 ID 为键存到数据库字典 player-states 中
+type PlayerState struct {
+	TrackID    string           `json:"track_id"` // 播放单集时为单集 ID
+	ItemType   string           `json:"item_type"`
+	ProgressMs int              `json:"progress_ms"`
+	Context    *PlaybackContext `json:"context"`
+	IsPlaying  bool             `json:"is_playing"`
+	DeviceName string           `json:"device_name"`
+	DeviceType string           `json:"device_type"` // 如 Computer Smartphone Speaker
+	Volume     int              `json:"volume"`
+	Shuffle    bool             `json:"shuffle"`
+	Repeat     string           `json:"repeat"` // off track context
+	SampledAt  string           `json:"sampled_at"`
+}
+
+// PlaybackState 是附加到播放记录上的设备与播放模式信息
+type PlaybackState struct {
+	DeviceName string `json:"device_name"`
+	DeviceType string `json:"device_type"`
+	Shuffle    bool   `json:"shuffle"`
+	Repeat     string `json:"repeat"`
+}
+
+// GetPlayerState 从 Spotify 获取当前播放器状态, 若没有活动的设备会返回 nil
+func (c *Client) GetPlayerState(dbc dbClient) (*PlayerState, error) {
+	if c.C == nil {
+		return nil, ErrNoSpotifyClient
+	}
+
+	ps, err := c.C.PlayerState(c.ctx(dbc), spotify.AdditionalTypes(spotify.EpisodeAdditionalType))
+	if err != nil {
+		return nil, err
+	}
+
+	if ps.Device.Name == "" {
+		return nil, nil
+	}
+
+	state := &PlayerState{
+		IsPlaying:  ps.Playing,
+		DeviceName: ps.Device.Name,
+		DeviceType: ps.Device.Type,
+		Volume:     int(ps.Device.Volume),
+		Shuffle:    ps.ShuffleState,
+		Repeat:     ps.RepeatState,
+		SampledAt:  time.Now().Format(time.DateTime),
+		ProgressMs: int(ps.Progress),
+		Context:    convertPlaybackContext(ps.PlaybackContext),
+	}
+
+	if ps.Item != nil {
+		state.TrackID = ps.Item.ID.String()
+		state.ItemType = ps.Item.Type
+	}
+
+	return state, nil
+}
+
+// savePlayerState 采样并存储播放器状态, 只保留每首曲目最后一次播放时的状态
+func (c *Client) savePlayerState(dbc dbClient) error {
+	state, err := c.GetPlayerState(dbc)
+	if err != nil {
+		return err
+	}
+
+	if state == nil || !state.IsPlaying || state.TrackID == "" {
+		return nil
+	}
+
+	// 最近播放不包括单集, 单集的播放记录只能从采样中得到
+	if state.ItemType == EntryTypeEpisode {
+		return savePendingEpisodePlay(dbc, state)
+	}
+
+	state.TrackID, err = getCanonicalTrackID(dbc, state.TrackID)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap("player-states", state.TrackID, string(j))
+}
+
+// RunPlayerStateCollector 按 interval 采样播放器状态, 可选, 需与 Run 一同在另一个 goroutine 中运行
+// interval 应小于大多数曲目的时长, 否则部分播放记录会缺少设备信息
+func (c *Client) RunPlayerStateCollector(dbc dbClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		err := c.doJob(dbc, "player-state", c.savePlayerState)
+		if err != nil {
+			slog.Warn("Spotify 获取或存储播放器状态失败", "error", err)
+		}
+	}
+}
+
+// getPlaybackState 返回一条播放记录对应的播放器状态, 若采样时间与播放时间相差过大或没有采样会返回 nil
+func getPlaybackState(dbc dbClient, entry PlaybackEntry) (*PlaybackState, error) {
+	s, err := dbc.GetMapStr("player-states", entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	state := &PlayerState{}
+
+	err = json.Unmarshal([]byte(s), state)
+	if err != nil {
+		return nil, err
+	}
+
+	sampledAt, err := time.Parse(time.DateTime, state.SampledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	playedAt, err := time.Parse(time.DateTime, entry.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// played_at 是播放结束的时间, 采样应发生在播放过程中
+	if sampledAt.Before(playedAt.Add(-time.Minute*15)) || sampledAt.After(playedAt.Add(time.Minute)) {
+		return nil, nil
+	}
+
+	return &PlaybackState{
+		DeviceName: state.DeviceName,
+		DeviceType: state.DeviceType,
+		Shuffle:    state.Shuffle,
+		Repeat:     state.Repeat,
+	}, nil
+}
+
+// GetPlaybackDeviceTypeCounts 返回一段时间内各设备类型的收听量(包括t1和t2), 没有设备信息的记录计入 DeviceTypeUnknown, 若其中一个日期没有数据会返回nil
+func (c *Client) GetPlaybackDeviceTypeCounts(dbc dbClient, t1, t2 time.Time) (map[string]int, error) {
+	entries, err := c.getPlaybackEntriesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		return nil, nil
+	}
+
+	counts := map[string]int{}
+
+	for _, entry := range entries {
+		counts[getDeviceType(entry.State)]++
+	}
+
+	return counts, nil
+}
+
+// GetDailyPlaybackDeviceTypeCounts 返回一段时间内每天各设备类型的收听量(包括t1和t2), 键为 time.DateOnly 格式的日期, 若其中一个日期没有数据会返回nil
+func (c *Client) GetDailyPlaybackDeviceTypeCounts(dbc dbClient, t1, t2 time.Time) (map[string]map[string]int, error) {
+	entries, err := c.getPlaybackEntriesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		return nil, nil
+	}
+
+	counts := map[string]map[string]int{}
+
+	for _, entry := range entries {
+		// 日期部分
+		day := entry.PlayedAt[:10]
+
+		if counts[day] == nil {
+			counts[day] = map[string]int{}
+		}
+
+		counts[day][getDeviceType(entry.State)]++
+	}
+
+	return counts, nil
+}
+
+// GetShufflePlaybackCounts 返回一段时间内随机播放的收听量与有播放器状态的总收听量(包括t1和t2)
+func (c *Client) GetShufflePlaybackCounts(dbc dbClient, t1, t2 time.Time) (shuffled int, total int, err error) {
+	entries, err := c.getPlaybackEntriesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, entry := range entries {
+		if entry.State == nil {
+			continue
+		}
+
+		total++
+
+		if entry.State.Shuffle {
+			shuffled++
+		}
+	}
+
+	return shuffled, total, nil
+}
+
+func getDeviceType(state *PlaybackState) string {
+	if state == nil || state.DeviceType == "" {
+		return DeviceTypeUnknown
+	}
+
+	return state.DeviceType
+}
@@ -0,0 +1,259 @@
+package spotify
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PlaybackEntry 的 Type 字段, 曲目为空以兼容旧的播放记录
+const (
+	EntryTypeTrack   = "track"
+	EntryTypeEpisode = "episode"
+)
+
+// pendingEpisodePlay 是采样到但尚未并入播放记录的单集播放, 以单集 ID 为键暂存到数据库字典 pending-episode-plays 中
+type pendingEpisodePlay struct {
+	ID              string           `json:"id"`
+	PlayedAt        string           `json:"played_at"` // 最后一次采样到播放的时间
+	StartProgressMs int              `json:"start_progress_ms"`
+	ProgressMs      int              `json:"progress_ms"`
+	Context         *PlaybackContext `json:"context"`
+	State           *PlaybackState   `json:"state"`
+}
+
+type PlayedEpisode struct {
+	Episode  `json:"episode"`
+	PlayedAt string `json:"played_at"`
+	MsPlayed int    `json:"ms_played"`
+}
+
+func isEpisode(entry PlaybackEntry) bool {
+	return entry.Type == EntryTypeEpisode
+}
+
+// savePendingEpisodePlay 暂存一次单集采样, 同一单集连续收听只会更新同一条记录
+func savePendingEpisodePlay(dbc dbClient, state *PlayerState) error {
+	s, err := dbc.GetMapStr("pending-episode-plays", state.TrackID)
+	if err != nil {
+		return err
+	}
+
+	play := &pendingEpisodePlay{
+		ID:              state.TrackID,
+		StartProgressMs: state.ProgressMs,
+		Context:         state.Context,
+		State: &PlaybackState{
+			DeviceName: state.DeviceName,
+			DeviceType: state.DeviceType,
+			Shuffle:    state.Shuffle,
+			Repeat:     state.Repeat,
+		},
+	}
+
+	if s != "" {
+		err = json.Unmarshal([]byte(s), play)
+		if err != nil {
+			return err
+		}
+	}
+
+	play.PlayedAt = state.SampledAt
+	play.ProgressMs = state.ProgressMs
+
+	j, err := json.Marshal(play)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap("pending-episode-plays", state.TrackID, string(j))
+}
+
+// getFinishedEpisodePlays 返回已经结束的单集播放, 即之后已经播放过曲目或一小时内没有再采样到的单集, 追加到播放记录后需调用 removePendingEpisodePlays
+// newestPlayedAt 为此次从 Spotify 获取到的最近一次播放的时间
+func getFinishedEpisodePlays(dbc dbClient, newestPlayedAt string) ([]PlaybackEntry, error) {
+	pending, err := dbc.GetMapAll("pending-episode-plays")
+	if err != nil {
+		return nil, err
+	}
+
+	idleBefore := time.Now().Add(-time.Hour).Format(time.DateTime)
+
+	var entries []PlaybackEntry
+
+	for _, s := range pending {
+		play := &pendingEpisodePlay{}
+
+		err = json.Unmarshal([]byte(s), play)
+		if err != nil {
+			return nil, err
+		}
+
+		if play.PlayedAt > newestPlayedAt && play.PlayedAt > idleBefore {
+			continue
+		}
+
+		entries = append(entries, PlaybackEntry{
+			ID:       play.ID,
+			PlayedAt: play.PlayedAt,
+			Context:  play.Context,
+			State:    play.State,
+			Type:     EntryTypeEpisode,
+			MsPlayed: max(play.ProgressMs-play.StartProgressMs, 0),
+		})
+	}
+
+	return entries, nil
+}
+
+// removePendingEpisodePlays 从暂存中移除已经追加到播放记录的单集播放, 追加后又采样到的会保留
+func removePendingEpisodePlays(dbc dbClient, entries []PlaybackEntry) error {
+	appended := map[string]string{}
+
+	for _, entry := range entries {
+		if isEpisode(entry) {
+			appended[entry.ID] = entry.PlayedAt
+		}
+	}
+
+	if len(appended) == 0 {
+		return nil
+	}
+
+	pending, err := dbc.GetMapAll("pending-episode-plays")
+	if err != nil {
+		return err
+	}
+
+	remaining := map[string]string{}
+
+	for id, s := range pending {
+		play := &pendingEpisodePlay{}
+
+		err = json.Unmarshal([]byte(s), play)
+		if err != nil {
+			return err
+		}
+
+		if playedAt, ok := appended[id]; !ok || play.PlayedAt != playedAt {
+			remaining[id] = s
+		}
+	}
+
+	if len(remaining) == len(pending) {
+		return nil
+	}
+
+	err = dbc.Delete("pending-episode-plays")
+	if err != nil {
+		return err
+	}
+
+	for id, s := range remaining {
+		err = dbc.SetMap("pending-episode-plays", id, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPlaybackEpisodes 返回播放记录中的单集, 曲目会被跳过, 若单集信息不存在会跳过
+func (c *Client) GetPlaybackEpisodes(dbc dbClient, start, stop int64) ([]PlayedEpisode, error) {
+	entries, err := getPlaybackEntries(dbc, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	var playedEpisodes []PlayedEpisode
+
+	for _, entry := range entries {
+		if !isEpisode(entry) {
+			continue
+		}
+
+		episode, err := c.getEpisodeCache(dbc, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if episode == nil {
+			continue
+		}
+
+		playedEpisodes = append(playedEpisodes, PlayedEpisode{*episode, entry.PlayedAt, entry.MsPlayed})
+	}
+
+	return playedEpisodes, nil
+}
+
+func (c *Client) getPlaybackEpisodesDuringATime(dbc dbClient, t1, t2 time.Time) ([]PlayedEpisode, error) {
+	rangeFromT1ToT2, err := c.GetPlaybackRangeDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if rangeFromT1ToT2 == nil {
+		return nil, nil
+	}
+
+	return c.GetPlaybackEpisodes(dbc, int64(rangeFromT1ToT2.Start), int64(rangeFromT1ToT2.End))
+}
+
+// GetTopEpisodesIDs 返回一段时间内的热门单集ID(包括t1和t2), 若其中一个日期没有数据会返回nil, limit为0则不限制
+func (c *Client) GetTopEpisodesIDs(dbc dbClient, t1, t2 time.Time, limit int) ([]Tops, error) {
+	pe, err := c.getPlaybackEpisodesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	episodeCounts := map[string]int{}
+
+	for _, episode := range pe {
+		episodeCounts[episode.ID]++
+	}
+
+	return sortTops(episodeCounts, limit), nil
+}
+
+// GetTopShowsIDs 返回一段时间内的热门播客ID(包括t1和t2), 若其中一个日期没有数据会返回nil, limit为0则不限制
+func (c *Client) GetTopShowsIDs(dbc dbClient, t1, t2 time.Time, limit int) ([]Tops, error) {
+	pe, err := c.getPlaybackEpisodesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	showCounts := map[string]int{}
+
+	for _, episode := range pe {
+		showCounts[episode.Show.ID]++
+	}
+
+	return sortTops(showCounts, limit), nil
+}
+
+// GetPodcastMinutes 返回一段时间内收听播客的分钟数(包括t1和t2), 没有收听时长的记录按单集时长计算
+func (c *Client) GetPodcastMinutes(dbc dbClient, t1, t2 time.Time) (int, error) {
+	pe, err := c.getPlaybackEpisodesDuringATime(dbc, t1, t2)
+	if err != nil {
+		return 0, err
+	}
+
+	ms := 0
+
+	for _, episode := range pe {
+		if episode.MsPlayed > 0 {
+			ms += episode.MsPlayed
+			continue
+		}
+
+		d, err := parseDuration(episode.Duration)
+		if err != nil {
+			return 0, err
+		}
+
+		ms += int(d.Milliseconds())
+	}
+
+	return ms / 60000, nil
+}
@@ -3,130 +3,449 @@ package spotify
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/zmb3/spotify/v2"
 )
 
 // PlaybackEntry 是数据库列表 playback-history 中的存储格式
+// ID 是曲目或单集的 ID, 本地文件为生成的 ID, 重链接的曲目为第一次获取信息时使用的 ID
 type PlaybackEntry struct {
-	ID       string `json:"id"`
-	PlayedAt string `json:"played_at"`
+	ID       string           `json:"id"`
+	PlayedAt string           `json:"played_at"`
+	Context  *PlaybackContext `json:"context,omitempty"`
+	State    *PlaybackState   `json:"state,omitempty"`
+	Type     string           `json:"type,omitempty"` // 为空时是曲目, 见 EntryType*
+	MsPlayed int              `json:"ms_played,omitempty"`
+	Source   string           `json:"source,omitempty"` // 为空时来自最近播放, 见 EntrySource*
+	// 以下仅 Spotify 数据导出中存在
+	Skipped     bool   `json:"skipped,omitempty"`
+	ReasonStart string `json:"reason_start,omitempty"`
+	ReasonEnd   string `json:"reason_end,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	// 仅从 Last.fm 导入的播放记录中存在, 见 lastFMMatchKey
+	MatchKey string `json:"match_key,omitempty"`
 }
 
-func (c *Client) getRecentlyPlayedTracksFromSpotify() ([]PlaybackEntry, error) {
-	recentlyPlayedTracks, err := c.C.PlayerRecentlyPlayedOpt(c.Ctx, &spotify.RecentlyPlayedOptions{Limit: 50})
-	if err != nil {
-		return nil, err
-	}
+// recentlyPlayedOverlap 是再次获取已存储的最后一次播放之前的时间, 用于去重与检测缺口
+const recentlyPlayedOverlap = time.Minute * 10
 
+// getRecentlyPlayedTracksFromSpotify 按游标获取 after 之后的所有最近播放, 按时间倒序排列, after 为零值时只获取最近 50 条
+func (c *Client) getRecentlyPlayedTracksFromSpotify(dbc dbClient, after time.Time) ([]PlaybackEntry, error) {
 	var playbackHistory []PlaybackEntry
 
-	for _, item := range recentlyPlayedTracks {
-		playbackHistory = append(playbackHistory, PlaybackEntry{item.Track.ID.String(), item.PlayedAt.Local().Format(time.DateTime)})
+	// Spotify 最多保留 50 条, 防止意外的无限循环
+	for page := 0; page < 10; page++ {
+		opt := &spotify.RecentlyPlayedOptions{Limit: 50}
+		if !after.IsZero() {
+			opt.AfterEpochMs = after.UnixMilli()
+		}
+
+		recentlyPlayedTracks, err := c.C.PlayerRecentlyPlayedOpt(c.ctx(dbc), opt)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageHistory []PlaybackEntry
+
+		for _, item := range recentlyPlayedTracks {
+			var id string
+
+			if isLocalTrack(&item.Track) {
+				id, err = saveLocalTrack(dbc, &item.Track)
+			} else {
+				id, err = getCanonicalTrackID(dbc, item.Track.ID.String())
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			pageHistory = append(pageHistory, PlaybackEntry{ID: id, PlayedAt: item.PlayedAt.Local().Format(time.DateTime), Context: convertPlaybackContext(item.PlaybackContext)})
+
+			if item.PlayedAt.After(after) {
+				after = item.PlayedAt
+			}
+		}
+
+		// 较新的一页放在前面
+		playbackHistory = append(pageHistory, playbackHistory...)
+
+		if len(recentlyPlayedTracks) < 50 || opt.AfterEpochMs == 0 {
+			break
+		}
 	}
 
 	return playbackHistory, nil
 }
 
-func (c *Client) truncate(dbc dbClient, playbackHistory []PlaybackEntry) ([]PlaybackEntry, error) {
-	lastPlayed, err := dbc.GetSliceByIndex("playback-history", -1)
+// playbackKey 以 ID 与精确到秒的播放时间作为播放记录的唯一标识
+func playbackKey(entry PlaybackEntry) (string, error) {
+	playedAt, err := time.ParseInLocation(time.DateTime, entry.PlayedAt, time.Local)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if lastPlayed == "" {
+	return entry.ID + "@" + strconv.FormatInt(playedAt.Unix(), 10), nil
+}
+
+// getLastRecentlyPlayed 返回已存储的最后一次来自最近播放的曲目, 以及最后一次播放, 不存在时为 nil
+// 单集与导入的播放记录不会出现在最近播放中
+func getLastRecentlyPlayed(tail []PlaybackEntry) (lastRecentlyPlayed *PlaybackEntry, last *PlaybackEntry) {
+	if len(tail) == 0 {
+		return nil, nil
+	}
+
+	for i := len(tail) - 1; i >= 0; i-- {
+		if tail[i].Source == EntrySourceRecentlyPlayed && !isEpisode(tail[i]) {
+			return &tail[i], &tail[len(tail)-1]
+		}
+	}
+
+	return nil, &tail[len(tail)-1]
+}
+
+// dedup 移除已存储的与早于最后一次播放的记录, tail 为已存储的最后若干条播放记录
+// 若获取到的记录中找不到已存储的最后一次来自最近播放的曲目, 且都比它更晚, 会记录缺口
+func (c *Client) dedup(dbc dbClient, tail, playbackHistory []PlaybackEntry) ([]PlaybackEntry, error) {
+	lastRecentlyPlayed, last := getLastRecentlyPlayed(tail)
+	if last == nil {
 		return playbackHistory, nil
 	}
 
-	pe := PlaybackEntry{}
+	stored := map[string]bool{}
 
-	err = json.Unmarshal([]byte(lastPlayed), &pe)
-	if err != nil {
-		return nil, err
+	for _, entry := range tail {
+		key, err := playbackKey(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		stored[key] = true
 	}
 
-	lastPlayedIndex := 0
+	var res []PlaybackEntry
+	lastRecentlyPlayedFound := false
 
-	for ; lastPlayedIndex < len(playbackHistory); lastPlayedIndex++ {
-		if playbackHistory[lastPlayedIndex].PlayedAt == pe.PlayedAt {
-			break
+	for _, entry := range playbackHistory {
+		key, err := playbackKey(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if stored[key] {
+			if lastRecentlyPlayed != nil && entry.ID == lastRecentlyPlayed.ID && entry.PlayedAt == lastRecentlyPlayed.PlayedAt {
+				lastRecentlyPlayedFound = true
+			}
+			continue
+		}
+
+		// 已存储的最后一次播放之前的记录无法按顺序追加
+		if entry.PlayedAt < last.PlayedAt {
+			continue
+		}
+
+		res = append(res, entry)
+	}
+
+	// 获取到的都比已存储的最后一次来自最近播放的曲目更晚, 之间的播放记录已经无法获取
+	if lastRecentlyPlayed != nil && !lastRecentlyPlayedFound && len(playbackHistory) > 0 && playbackHistory[len(playbackHistory)-1].PlayedAt > lastRecentlyPlayed.PlayedAt {
+		err := c.savePlaybackGap(dbc, lastRecentlyPlayed.PlayedAt, playbackHistory[len(playbackHistory)-1].PlayedAt)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return playbackHistory[:lastPlayedIndex], nil
+	return res, nil
 }
 
-// saveRecentlyPlayedTracks 追加最近收听的歌曲并统计每日收听量, 并以 *Map 的 JSON 格式存储信息
-func (c *Client) saveRecentlyPlayedTracks(dbc dbClient) error {
-	recentlyPlayedTracks, err := c.getRecentlyPlayedTracksFromSpotify()
+// pendingRecentlyPlayed 是正在追加到播放记录的最近播放, 存到数据库 pending-recently-played 中
+// 追加之后的统计全部完成后才会删除, 失败重试时会先完成上一次的统计
+type pendingRecentlyPlayed struct {
+	Entries []string `json:"entries"` // 追加到 playback-history 的 JSON
+	Stage   int      `json:"stage"`
+}
+
+// pendingRecentlyPlayed 的 Stage
+const (
+	recentlyPlayedStageAppending = iota // 正在追加, 不确定是否已经追加
+	recentlyPlayedStageRanges           // 已移除暂存的单集并统计每日收听量
+	recentlyPlayedStageCounts           // 已统计收听量
+)
+
+func savePendingRecentlyPlayed(dbc dbClient, pending *pendingRecentlyPlayed) error {
+	j, err := json.Marshal(pending)
 	if err != nil {
 		return err
 	}
 
-	truncatedPlaybackHistory, err := c.truncate(dbc, recentlyPlayedTracks)
+	return dbc.SetString("pending-recently-played", string(j), nil)
+}
+
+// resumeRecentlyPlayed 完成上一次中断的追加之后的统计, 若上一次没有追加成功则丢弃, 之后会重新获取
+func (c *Client) resumeRecentlyPlayed(dbc dbClient) error {
+	s, err := dbc.GetString("pending-recently-played")
 	if err != nil {
 		return err
 	}
 
-	if len(truncatedPlaybackHistory) == 0 {
+	if s == "" {
 		return nil
 	}
 
-	slices.Reverse(truncatedPlaybackHistory)
+	pending := &pendingRecentlyPlayed{}
 
-	days := map[string]int{}
-	var playbackHistory []string
+	err = json.Unmarshal([]byte(s), pending)
+	if err != nil {
+		return err
+	}
 
-	for _, entry := range truncatedPlaybackHistory {
-		j, err := json.Marshal(&PlaybackEntry{entry.ID, entry.PlayedAt})
+	if pending.Stage == recentlyPlayedStageAppending && len(pending.Entries) > 0 {
+		tail, err := dbc.GetSlice("playback-history", -int64(len(pending.Entries)+50), -1)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(tail, pending.Entries[len(pending.Entries)-1]) {
+			slog.Warn("上一次追加最近播放失败, 将重新获取", "数量", len(pending.Entries))
+			return dbc.Delete("pending-recently-played")
+		}
+	}
+
+	slog.Info("继续完成上一次追加最近播放之后的统计", "数量", len(pending.Entries))
+
+	return c.finishRecentlyPlayed(dbc, pending)
+}
+
+// finishRecentlyPlayed 完成追加之后的统计, 每完成一步会更新 pending 以免重试时重复统计
+func (c *Client) finishRecentlyPlayed(dbc dbClient, pending *pendingRecentlyPlayed) error {
+	entries := make([]PlaybackEntry, len(pending.Entries))
+
+	for i, raw := range pending.Entries {
+		err := json.Unmarshal([]byte(raw), &entries[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	if pending.Stage < recentlyPlayedStageRanges {
+		err := removePendingEpisodePlays(dbc, entries)
 		if err != nil {
 			return err
 		}
 
-		// 日期部分
-		days[entry.PlayedAt[:10]]++
+		days := map[string]int{}
+
+		for _, entry := range entries {
+			// 日期部分
+			days[entry.PlayedAt[:10]]++
+		}
+
+		for day, count := range days {
+			t, err := time.Parse(time.DateOnly, day)
+			if err != nil {
+				return err
+			}
+
+			err = c.savePlaybackRangeOnADay(dbc, t, count)
+			if err != nil {
+				if errors.Is(err, errDailyPlaybackRangesNotMatch) {
+					break
+				}
+				return err
+			}
+		}
+
+		pending.Stage = recentlyPlayedStageRanges
 
-		playbackHistory = append(playbackHistory, string(j))
+		err = savePendingRecentlyPlayed(dbc, pending)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = dbc.AppendSlice("playback-history", playbackHistory)
+	playedTracks, err := c.getPlayedTracks(dbc, entries)
 	if err != nil {
 		return err
 	}
 
-	for day, count := range days {
-		t, err := time.Parse(time.DateOnly, day)
+	if pending.Stage < recentlyPlayedStageCounts {
+		err = c.savePlaybackCounts(dbc, playedTracks)
 		if err != nil {
 			return err
 		}
 
-		err = c.savePlaybackRangeOnADay(dbc, t, count)
+		pending.Stage = recentlyPlayedStageCounts
+
+		err = savePendingRecentlyPlayed(dbc, pending)
 		if err != nil {
-			if errors.Is(err, errDailyPlaybackRangesNotMatch) {
-				break
+			return err
+		}
+	}
+
+	c.publishNewPlays(playedTracks)
+
+	if len(playedTracks) > 0 {
+		c.emitWebhook(dbc, WebhookNewPlays, map[string]any{"plays": playedTracks})
+	}
+
+	return dbc.Delete("pending-recently-played")
+}
+
+// getPlayedTracks 返回 entries 中的曲目, 同时确保单集信息已存储, 信息不存在的曲目会被跳过
+func (c *Client) getPlayedTracks(dbc dbClient, entries []PlaybackEntry) ([]PlayedTrack, error) {
+	var playedTracks []PlayedTrack
+
+	for _, entry := range entries {
+		if isEpisode(entry) {
+			_, err := c.getEpisodeCache(dbc, entry.ID)
+			if err != nil {
+				return nil, err
 			}
+			continue
+		}
+
+		track, err := c.getTrackCache(dbc, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if track == nil {
+			continue
+		}
+
+		playedTracks = append(playedTracks, PlayedTrack{*track, entry.PlayedAt})
+	}
+
+	return playedTracks, nil
+}
+
+// saveRecentlyPlayedTracks 追加最近收听的歌曲并统计每日收听量, 并以 *Map 的 JSON 格式存储信息, 重复运行不会重复追加
+// 追加前会先获取所有信息, 追加后的统计中断时下一次运行会继续完成, 见 pendingRecentlyPlayed
+func (c *Client) saveRecentlyPlayedTracks(dbc dbClient) error {
+	err := c.resumeRecentlyPlayed(dbc)
+	if err != nil {
+		return err
+	}
+
+	tail, err := getPlaybackEntries(dbc, -50, -1)
+	if err != nil {
+		return err
+	}
+
+	after := time.Time{}
+
+	lastRecentlyPlayed, last := getLastRecentlyPlayed(tail)
+	if lastRecentlyPlayed == nil {
+		lastRecentlyPlayed = last
+	}
+
+	if lastRecentlyPlayed != nil {
+		after, err = time.ParseInLocation(time.DateTime, lastRecentlyPlayed.PlayedAt, time.Local)
+		if err != nil {
 			return err
 		}
+
+		after = after.Add(-recentlyPlayedOverlap)
 	}
 
-	var truncatedRecentlyPlayedTracks []PlayedTrack
+	fetchedAt := time.Now()
+
+	recentlyPlayedTracks, err := c.getRecentlyPlayedTracksFromSpotify(dbc, after)
+	if err != nil {
+		return err
+	}
+
+	truncatedPlaybackHistory, err := c.dedup(dbc, tail, recentlyPlayedTracks)
+	if err != nil {
+		return err
+	}
+
+	newestPlayedAt := ""
+	if len(recentlyPlayedTracks) > 0 {
+		newestPlayedAt = recentlyPlayedTracks[0].PlayedAt
+	}
+
+	finishedEpisodePlays, err := getFinishedEpisodePlays(dbc, newestPlayedAt)
+	if err != nil {
+		return err
+	}
+
+	truncatedPlaybackHistory = append(truncatedPlaybackHistory, finishedEpisodePlays...)
+
+	if len(truncatedPlaybackHistory) == 0 {
+		return saveRecentlyPlayedTime(dbc, fetchedAt)
+	}
+
+	slices.Reverse(truncatedPlaybackHistory)
+	slices.SortStableFunc(truncatedPlaybackHistory, func(a, b PlaybackEntry) int {
+		return strings.Compare(a.PlayedAt, b.PlayedAt)
+	})
+
+	// 追加前获取信息, 之后的统计不会因为请求 Spotify 失败而中断
+	_, err = c.getPlayedTracks(dbc, truncatedPlaybackHistory)
+	if err != nil {
+		return err
+	}
+
+	pending := &pendingRecentlyPlayed{}
 
 	for _, entry := range truncatedPlaybackHistory {
-		track, err := c.getTrackCache(dbc, entry.ID)
+		// 仅在运行 RunPlayerStateCollector 时存在, 单集在采样时已经附加
+		if !isEpisode(entry) {
+			entry.State, err = getPlaybackState(dbc, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		j, err := json.Marshal(&entry)
 		if err != nil {
 			return err
 		}
 
-		truncatedRecentlyPlayedTracks = append(truncatedRecentlyPlayedTracks, PlayedTrack{*track, entry.PlayedAt})
+		pending.Entries = append(pending.Entries, string(j))
+	}
+
+	err = savePendingRecentlyPlayed(dbc, pending)
+	if err != nil {
+		return err
+	}
+
+	err = dbc.AppendSlice("playback-history", pending.Entries)
+	if err != nil {
+		return err
+	}
+
+	err = c.finishRecentlyPlayed(dbc, pending)
+	if err != nil {
+		return err
+	}
+
+	return saveRecentlyPlayedTime(dbc, fetchedAt)
+}
+
+// saveRecentlyPlayedTime 记录最近一次成功获取最近播放的时间, 此时间之前的播放记录视为已经完整, 见 getRecentlyPlayedTime
+func saveRecentlyPlayedTime(dbc dbClient, t time.Time) error {
+	return dbc.SetMap("updated-times", "recently-played", t.Format(time.DateTime))
+}
+
+// getRecentlyPlayedTime 返回最近一次成功获取最近播放的时间, 从未成功时为零值
+func getRecentlyPlayedTime(dbc dbClient) (time.Time, error) {
+	s, err := dbc.GetMapStr("updated-times", "recently-played")
+	if err != nil || s == "" {
+		return time.Time{}, err
 	}
 
-	return c.savePlaybackCounts(dbc, truncatedRecentlyPlayedTracks)
+	return time.ParseInLocation(time.DateTime, s, time.Local)
 }
 
+// GetPlaybackHistory 返回播放记录中的曲目, 单集会被跳过, 见 GetPlaybackEpisodes
 func (c *Client) GetPlaybackHistory(dbc dbClient, start, stop int64) ([]PlayedTrack, error) {
-	playbackHistory, err := dbc.GetSlice("playback-history", start, stop)
+	playbackHistory, err := getPlaybackEntries(dbc, start, stop)
 	if err != nil {
 		return nil, err
 	}
@@ -134,8 +453,11 @@ func (c *Client) GetPlaybackHistory(dbc dbClient, start, stop int64) ([]PlayedTr
 	var playedTracks []PlayedTrack
 
 	for _, entry := range playbackHistory {
-		// ID 部分
-		track, err := c.getTrackCache(dbc, entry[7:29])
+		if isEpisode(entry) {
+			continue
+		}
+
+		track, err := c.getTrackCache(dbc, entry.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -144,14 +466,13 @@ func (c *Client) GetPlaybackHistory(dbc dbClient, start, stop int64) ([]PlayedTr
 			continue
 		}
 
-		// 日期部分
-		playedTracks = append(playedTracks, PlayedTrack{*track, entry[44:63]})
+		playedTracks = append(playedTracks, PlayedTrack{*track, entry.PlayedAt})
 	}
 
 	return playedTracks, nil
 }
 
-// GetPlaybackHistoryByIndex 若播放记录中的 ID 对应的 Track 不存在会返回 nil
+// GetPlaybackHistoryByIndex 若播放记录中的 ID 对应的 Track 不存在或是单集会返回 nil
 func (c *Client) GetPlaybackHistoryByIndex(dbc dbClient, index int64) (*PlayedTrack, error) {
 	entry, err := dbc.GetSliceByIndex("playback-history", index)
 	if err != nil {
@@ -169,6 +490,10 @@ func (c *Client) GetPlaybackHistoryByIndex(dbc dbClient, index int64) (*PlayedTr
 		return nil, err
 	}
 
+	if isEpisode(*pe) {
+		return nil, nil
+	}
+
 	track, err := c.getTrackCache(dbc, pe.ID)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,252 @@
+package spotify
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 歌曲(Song)是同一首歌的所有版本(重制版 混音版 不同专辑中的重复曲目等)组成的逻辑曲目, 以第一个被记录的版本的曲目 ID 作为歌曲 ID
+// 数据库字典 song-ids 以曲目 ID 为键, 歌曲 ID 为值
+// 数据库字典 song-keys 以 "isrc:ISRC" 与 "name:规范化名称|第一位艺术家名称" 为键, 歌曲 ID 为值
+// 数据库字典 song-versions 以歌曲 ID 为键, 所有版本的曲目 ID 的 JSON 数组为值
+
+var trackNameBracketsRegexp = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]`)
+
+// normalizeTrackName 移除名称中含有 garbageWords 的括号与 " - " 后缀, 并转换为小写
+// 如 "Song - 2011 Remaster" 与 "Song (Remastered)" 均为 "song"
+func normalizeTrackName(name string) string {
+	name = strings.ToLower(name)
+
+	name = trackNameBracketsRegexp.ReplaceAllStringFunc(name, func(s string) string {
+		if containsGarbageWord(s) {
+			return ""
+		}
+		return s
+	})
+
+	parts := strings.Split(name, " - ")
+	for i := 1; i < len(parts); i++ {
+		if containsGarbageWord(parts[i]) {
+			parts = parts[:i]
+			break
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(parts, " - "))
+}
+
+func containsGarbageWord(s string) bool {
+	for _, word := range garbageWords {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSongKeys 返回用于归并版本的键, ISRC 为空时只有名称键
+func getSongKeys(track *Track) []string {
+	var keys []string
+
+	if track.ISRC != "" {
+		keys = append(keys, "isrc:"+strings.ToUpper(track.ISRC))
+	}
+
+	firstArtist := ""
+	if len(track.Artists) > 0 {
+		firstArtist = strings.ToLower(track.Artists[0].Name)
+	}
+
+	return append(keys, "name:"+normalizeTrackName(track.Name)+"|"+firstArtist)
+}
+
+// GetSongID 返回曲目所属歌曲的 ID, 只读取数据库, 曲目还没有被归入歌曲时会返回空字符串
+func (c *Client) GetSongID(dbc dbClient, trackID string) (string, error) {
+	songID, err := dbc.GetMapStr("song-ids", trackID)
+	if err != nil {
+		return "", err
+	}
+
+	if songID != "" {
+		return songID, nil
+	}
+
+	// 重链接后的 ID 按原 ID 处理
+	canonicalID, err := getCanonicalTrackID(dbc, trackID)
+	if err != nil {
+		return "", err
+	}
+
+	if canonicalID == trackID {
+		return "", nil
+	}
+
+	return dbc.GetMapStr("song-ids", canonicalID)
+}
+
+// saveSongID 返回曲目所属歌曲的 ID, 曲目第一次出现时会按 ISRC 或规范化名称与第一位艺术家归入已有的歌曲, 否则成为新的歌曲
+// 只在保存收听量时调用, 若曲目信息不存在会返回空字符串
+func (c *Client) saveSongID(dbc dbClient, trackID string) (string, error) {
+	songID, err := dbc.GetMapStr("song-ids", trackID)
+	if err != nil {
+		return "", err
+	}
+
+	if songID != "" {
+		return songID, nil
+	}
+
+	track, err := c.getTrackCache(dbc, trackID)
+	if err != nil {
+		return "", err
+	}
+
+	if track == nil {
+		return "", nil
+	}
+
+	// 重链接后的 ID 按原 ID 处理
+	if track.ID != trackID {
+		return c.saveSongID(dbc, track.ID)
+	}
+
+	keys := getSongKeys(track)
+
+	for _, key := range keys {
+		songID, err = dbc.GetMapStr("song-keys", key)
+		if err != nil {
+			return "", err
+		}
+
+		if songID != "" {
+			break
+		}
+	}
+
+	if songID == "" {
+		songID = trackID
+	}
+
+	for _, key := range keys {
+		exists, err := dbc.CheckIfMapFieldExists("song-keys", key)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			err = dbc.SetMap("song-keys", key, songID)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	versions, err := c.GetSongVersionsIDs(dbc, songID)
+	if err != nil {
+		return "", err
+	}
+
+	j, err := json.Marshal(append(versions, trackID))
+	if err != nil {
+		return "", err
+	}
+
+	err = dbc.SetMap("song-versions", songID, string(j))
+	if err != nil {
+		return "", err
+	}
+
+	err = dbc.SetMap("song-ids", trackID, songID)
+	if err != nil {
+		return "", err
+	}
+
+	return songID, nil
+}
+
+// GetSongVersionsIDs 返回歌曲的所有已记录版本的曲目 ID, 若歌曲不存在会返回 nil
+func (c *Client) GetSongVersionsIDs(dbc dbClient, songID string) ([]string, error) {
+	s, err := dbc.GetMapStr("song-versions", songID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	var versions []string
+
+	err = json.Unmarshal([]byte(s), &versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetTrackPlaybackCount 返回曲目(单一版本)的总收听量
+func (c *Client) GetTrackPlaybackCount(dbc dbClient, trackID string) (int64, error) {
+	return dbc.GetMapInt64("track-playback-counts", trackID)
+}
+
+// GetSongPlaybackCount 返回歌曲(所有版本)的总收听量
+func (c *Client) GetSongPlaybackCount(dbc dbClient, songID string) (int64, error) {
+	return dbc.GetMapInt64("song-playback-counts", songID)
+}
+
+// saveSongPlaybackCount 存储歌曲的收听量
+func (c *Client) saveSongPlaybackCount(dbc dbClient, track PlayedTrack) error {
+	songID, err := c.saveSongID(dbc, track.ID)
+	if err != nil {
+		return err
+	}
+
+	if songID == "" {
+		return nil
+	}
+
+	counts, err := dbc.GetMapInt64("song-playback-counts", songID)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap("song-playback-counts", songID, strconv.Itoa(int(counts+1)))
+}
+
+// GetTopSongsIDs 返回一段时间内的热门歌曲ID(包括t1和t2), 同一首歌的不同版本合并计算, 若其中一个日期没有数据会返回nil, limit为0则不限制
+func (c *Client) GetTopSongsIDs(dbc dbClient, t1, t2 time.Time, limit int) ([]Tops, error) {
+	rangeFromT1ToT2, err := c.GetPlaybackRangeDuringATime(dbc, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+
+	if rangeFromT1ToT2 == nil {
+		return nil, nil
+	}
+
+	ph, err := c.GetPlaybackHistory(dbc, int64(rangeFromT1ToT2.Start), int64(rangeFromT1ToT2.End))
+	if err != nil {
+		return nil, err
+	}
+
+	songCounts := map[string]int{}
+
+	for _, track := range ph {
+		songID, err := c.GetSongID(dbc, track.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if songID == "" {
+			continue
+		}
+
+		songCounts[songID]++
+	}
+
+	return sortTops(songCounts, limit), nil
+}
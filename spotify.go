@@ -3,20 +3,38 @@ package spotify
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/zmb3/spotify/v2"
 	"log/slog"
+	"sync"
 	"time"
 )
 
+// ErrNoSpotifyClient 表示 Client.C 为 nil, 只能读取数据库中已有的数据
+var ErrNoSpotifyClient = errors.New("没有 Spotify 客户端, 无法请求 Spotify")
+
 type Client struct {
-	C   *spotify.Client
+	C   *spotify.Client // 为 nil 时为只读模式, 需要请求 Spotify 的操作返回 ErrNoSpotifyClient
 	Ctx context.Context
+
+	// CorrectionStrategies 是 Spotify 曲目榜补救时依次尝试的策略, 如 CorrectionISRC, 为空时使用全部策略
+	CorrectionStrategies []string
+
+	// Webhooks 是接收事件的地址, 需要同时运行 RunWebhookDispatcher
+	Webhooks []Webhook
+
+	liveOnce sync.Once
+	liveHub  *liveHub
+
+	webhookMu sync.Mutex
 }
 
 const (
-	TypeArtist = 'a'
-	TypeTrack  = 'i'
-	TypeAlbum  = 'A'
+	TypeArtist  = 'a'
+	TypeTrack   = 'i'
+	TypeAlbum   = 'A'
+	TypeShow    = 's'
+	TypeEpisode = 'e'
 )
 
 type Artist struct {
@@ -35,7 +53,8 @@ type Track struct {
 	ID       string   `json:"id"`
 	Name     string   `json:"name"`
 	//ExternalIDs map[string]string `json:"external_ids"`
-	Popularity int `json:"popularity"`
+	Popularity int    `json:"popularity"`
+	ISRC       string `json:"isrc,omitempty"` // 同一录音的不同版本可能有相同的 ISRC, 本地文件与旧的缓存为空
 	//IsPlayable  *bool             `json:"is_playable"`
 	//LinkedFrom  *spotify.LinkedFromInfo   `json:"linked_from"
 }
@@ -55,6 +74,24 @@ type Album struct {
 	//ExternalIDs map[string]string `json:"external_ids"`
 }
 
+type Show struct {
+	Name      string          `json:"name"`
+	ID        string          `json:"id"`
+	Publisher string          `json:"publisher"`
+	MediaType string          `json:"media_type"`
+	Images    []spotify.Image `json:"images"`
+}
+
+// Episode 是播客的单集
+type Episode struct {
+	Show        Show            `json:"show"`
+	Duration    string          `json:"duration"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	ReleaseDate string          `json:"release_date"`
+	Images      []spotify.Image `json:"images"`
+}
+
 func (a *Artist) toMap() *ArtistMap {
 	return &ArtistMap{
 		Name:       a.Name,
@@ -83,6 +120,26 @@ func (t *Track) toMap() *TrackMap {
 		Duration:   t.Duration,
 		Name:       t.Name,
 		Popularity: t.Popularity,
+		ISRC:       t.ISRC,
+	}
+}
+
+func (s *Show) toMap() *ShowMap {
+	return &ShowMap{
+		Name:      s.Name,
+		Publisher: s.Publisher,
+		MediaType: s.MediaType,
+		Images:    s.Images,
+	}
+}
+
+func (e *Episode) toMap() *EpisodeMap {
+	return &EpisodeMap{
+		ShowID:      e.Show.ID,
+		Duration:    e.Duration,
+		Name:        e.Name,
+		ReleaseDate: e.ReleaseDate,
+		Images:      e.Images,
 	}
 }
 
@@ -95,39 +152,35 @@ func (c *Client) runGroupShort(dbc dbClient) {
 	slog.Info("开始保存播放记录与其它信息, 请勿在结束前退出程序")
 	defer slog.Info("保存播放记录与其它信息结束")
 
-	err := c.saveRecentlyPlayedTracks(dbc)
-	for err != nil {
-		slog.Warn("Spotify 获取或存储最近播放失败, 一分钟后重试", "error", err)
-		time.Sleep(time.Minute)
-		err = c.saveRecentlyPlayedTracks(dbc)
-	}
-
-	err = c.saveHourlyPlaybackCounts(dbc)
-	for err != nil {
-		slog.Warn("Spotify 存储每小时收听量失败, 一分钟后重试", "error", err)
-		time.Sleep(time.Minute)
-		err = c.saveHourlyPlaybackCounts(dbc)
-	}
-
+	c.runJob(dbc, "recently-played", "Spotify 获取或存储最近播放失败", c.saveRecentlyPlayedTracks)
+	c.runJob(dbc, "hourly-playback-counts", "Spotify 存储每小时收听量失败", c.saveHourlyPlaybackCounts)
+	// 在追加最近播放之后运行, 周期结束后一小时内即可存储
+	c.runJob(dbc, "charts", "存储排行榜失败", c.saveCharts)
 }
 
 func (c *Client) runGroupLong(dbc dbClient) {
-	err := c.saveTopArtists(dbc)
-	for err != nil {
-		slog.Warn("Spotify 获取或存储热门艺术家失败, 一分钟后重试", "error", err)
-		time.Sleep(time.Minute)
-		err = c.saveTopArtists(dbc)
-	}
+	c.runJob(dbc, "top-snapshots", "Spotify 获取或存储排行榜失败", c.saveTopSnapshots)
+}
+
+// runJob 运行一个定时任务, 失败时一分钟后重试直到成功, job 用于指标与 span
+func (c *Client) runJob(dbc dbClient, job, failedMsg string, f func(dbClient) error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			jobRetries.WithLabelValues(job).Inc()
+		}
+
+		err := c.doJob(dbc, job, f)
+		if err == nil {
+			return
+		}
 
-	err = c.saveTopTracks(dbc)
-	for err != nil {
-		slog.Warn("Spotify 获取或存储热门曲目失败, 一分钟后重试", "error", err)
+		slog.Warn(failedMsg+", 一分钟后重试", "error", err)
 		time.Sleep(time.Minute)
-		err = c.saveTopTracks(dbc)
 	}
 }
 
 func (c *Client) Run(dbc dbClient) {
+	c.runJob(dbc, "playback-history-rewrite", "恢复播放记录失败", c.restorePlaybackHistory)
 	c.runGroupShort(dbc)
 	c.runGroupLong(dbc)
 
@@ -240,7 +293,45 @@ func (c *Client) convertTrack(dbc dbClient, track *spotify.FullTrack) (*Track, e
 		ID:         track.ID.String(),
 		Name:       track.Name,
 		Popularity: int(track.Popularity),
+		ISRC:       track.ExternalIDs["isrc"],
+	}, nil
+}
+
+func (c *Client) convertShow(show *spotify.SimpleShow) *Show {
+	return &Show{
+		Name:      show.Name,
+		ID:        show.ID.String(),
+		Publisher: show.Publisher,
+		MediaType: show.MediaType,
+		Images:    show.Images,
+	}
+}
+
+func (c *Client) convertEpisode(dbc dbClient, episode *spotify.EpisodePage) (*Episode, error) {
+	show, err := c.getShowCache(dbc, episode.Show.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Episode{
+		Show:        *show,
+		Duration:    time.UnixMilli(int64(episode.Duration_ms)).UTC().Format(time.TimeOnly),
+		ID:          episode.ID.String(),
+		Name:        episode.Name,
+		ReleaseDate: episode.ReleaseDate,
+		Images:      episode.Images,
 	}, nil
 }
 
+// parseDuration 解析 Track 与 Episode 中 time.TimeOnly 格式的时长
+func parseDuration(duration string) (time.Duration, error) {
+	t, err := time.Parse(time.TimeOnly, duration)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+// garbageWords 出现在括号或 " - " 之后时, 视为同一首歌的不同版本, 见 normalizeTrackName
 var garbageWords = []string{"remastered", "remaster", "remix", "reissue"}
@@ -0,0 +1,316 @@
+package spotify
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Spotify 排行榜快照存储在数据库字典 {时间范围}-top-{类型} 中, 如 monthly-top-artists, 以更新当天的 time.DateOnly 格式为键, ID 的 JSON 数组为值
+// 最新快照的日期存储在数据库字典 updated-times 中, 键与快照的字典相同
+
+// refreshPolicy 根据上一次更新的日期判断是否需要更新快照
+type refreshPolicy func(last, now time.Time) bool
+
+// refreshEvery 距上一次更新满 days 天后更新
+func refreshEvery(days int) refreshPolicy {
+	return func(last, now time.Time) bool {
+		return !now.Before(last.AddDate(0, 0, days))
+	}
+}
+
+// refreshMonthly 进入新的月份后更新
+func refreshMonthly(last, now time.Time) bool {
+	return now.Year()*12+int(now.Month()) > last.Year()*12+int(last.Month())
+}
+
+// topTerm 是 Spotify 排行榜的一个时间范围
+type topTerm struct {
+	name      string // TermMonthly 等
+	label     string // 用于日志
+	timeRange spotify.Range
+	months    int // 时间范围大约覆盖的月数, 用于从播放记录中统计
+	refresh   refreshPolicy
+}
+
+// lookback 返回与时间范围对应的开始时间
+func (t topTerm) lookback(tn time.Time) time.Time {
+	return tn.AddDate(0, -t.months, 0)
+}
+
+// topEntity 是 Spotify 排行榜的一个类型
+// source 不为空时由该类型的快照推导, 与其使用相同的日期, 不按刷新策略更新, 应排在 source 之后
+type topEntity struct {
+	name   string // ChartEntityArtists 等
+	label  string // 用于日志
+	source string
+	fetch  func(c *Client, dbc dbClient, term topTerm, tn time.Time) ([]string, error)
+}
+
+var topSnapshotTerms = []topTerm{
+	{TermMonthly, "月榜", spotify.ShortTermRange, 1, refreshEvery(7)},
+	{TermHalfYearly, "半年榜", spotify.MediumTermRange, 6, refreshMonthly},
+	{TermYearly, "年榜", spotify.LongTermRange, 12, refreshMonthly},
+}
+
+var topSnapshotEntities = []topEntity{
+	{ChartEntityArtists, "艺术家", "", (*Client).fetchTopArtists},
+	{ChartEntityTracks, "曲目", "", (*Client).fetchTopTracks},
+	{ChartEntityAlbums, "专辑", ChartEntityTracks, (*Client).deriveTopAlbums},
+}
+
+// saveTopSnapshots 按刷新策略更新所有 Spotify 排行榜快照
+func (c *Client) saveTopSnapshots(dbc dbClient) error {
+	tn := time.Now()
+
+	for _, entity := range topSnapshotEntities {
+		for _, term := range topSnapshotTerms {
+			err := c.saveTopSnapshot(dbc, term, entity, tn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) saveTopSnapshot(dbc dbClient, term topTerm, entity topEntity, tn time.Time) error {
+	key := topSnapshotKey(term.name, entity.name)
+
+	last, err := dbc.GetMapStr("updated-times", key)
+	if err != nil {
+		return err
+	}
+
+	date := tn.Format(time.DateOnly)
+
+	if entity.source != "" {
+		date, err = dbc.GetMapStr("updated-times", topSnapshotKey(term.name, entity.source))
+		if err != nil {
+			return err
+		}
+
+		if date == "" || date == last {
+			return nil
+		}
+	} else if last != "" {
+		t, err := time.ParseInLocation(time.DateOnly, last, time.Local)
+		if err != nil {
+			return err
+		}
+
+		if !term.refresh(t, tn) {
+			return nil
+		}
+	}
+
+	slog.Debug("正在更新 Spotify 排行榜", "排行榜", entity.label+term.label)
+
+	ids, err := entity.fetch(c, dbc, term, tn)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	err = dbc.SetMap(key, date, string(j))
+	if err != nil {
+		return err
+	}
+
+	err = dbc.SetMap("updated-times", key, date)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("Spotify 排行榜更新成功", "排行榜", entity.label+term.label)
+
+	c.emitWebhook(dbc, WebhookTopSnapshotUpdated, map[string]any{"term": term.name, "entity": entity.name, "date": date, "ids": ids})
+
+	return nil
+}
+
+func (c *Client) fetchTopArtists(dbc dbClient, term topTerm, tn time.Time) ([]string, error) {
+	fap, err := c.C.CurrentUsersTopArtists(c.ctx(dbc), spotify.Limit(50), spotify.Timerange(term.timeRange))
+	if err != nil {
+		return nil, err
+	}
+
+	var artists []string
+
+	for _, artist := range fap.Artists {
+		exists, err := dbc.CheckIfMapFieldExists("spotify-ids", artist.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			err = saveID(dbc, artist.ID.String(), c.convertArtist(&artist).toMap())
+			if err != nil {
+				return nil, err
+			}
+		}
+		artists = append(artists, artist.ID.String())
+	}
+
+	return artists, nil
+}
+
+func (c *Client) fetchTopTracks(dbc dbClient, term topTerm, tn time.Time) ([]string, error) {
+	ftp, err := c.C.CurrentUsersTopTracks(c.ctx(dbc), spotify.Limit(50), spotify.Timerange(term.timeRange))
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []string
+	var corrections []TrackCorrection
+	// 第一次需要补救时统计
+	var counts map[string]int
+
+	for _, track := range ftp.Tracks {
+		exists, err := dbc.CheckIfMapFieldExists("spotify-ids", track.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			converted, err := c.convertTrack(dbc, &track)
+			if err != nil {
+				return nil, err
+			}
+
+			err = saveID(dbc, track.ID.String(), converted.toMap())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Spotify 的一个 Bug: 有的曲目会被重置版混音版的同名曲目顶替, 排行榜上统计会混淆
+		saved, err := c.C.UserHasTracks(c.ctx(dbc), track.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if saved[0] {
+			tracks = append(tracks, track.ID.String())
+			continue
+		}
+
+		slog.Info("此歌曲未点赞, 可能是 Spotify 的 Bug, 进行补救", "名称", track.Name, "ID", track.ID, "艺术家", track.Artists[0].Name)
+
+		if counts == nil {
+			counts, err = c.getTrackCounts(dbc, term.lookback(tn), tn)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		id, strategy, err := c.correctTrack(dbc, track, counts)
+		if err != nil {
+			return nil, err
+		}
+
+		if id == "" {
+			slog.Info("补救失败, 未替换")
+			continue
+		}
+
+		slog.Info("补救成功, 替换为", "ID", id, "策略", strategy)
+
+		tracks = append(tracks, id)
+		corrections = append(corrections, TrackCorrection{
+			Rank:          len(tracks),
+			OriginalID:    track.ID.String(),
+			ReplacementID: id,
+			Strategy:      strategy,
+			CorrectedAt:   tn.Format(time.DateTime),
+		})
+	}
+
+	if len(corrections) > 0 {
+		err = saveTrackCorrections(dbc, term.name, tn.Format(time.DateOnly), corrections)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tracks, nil
+}
+
+// deriveTopAlbums 由 Spotify 曲目榜的最新快照推导专辑榜, Spotify 没有专辑榜
+// 曲目按排名计分(第一名 50 分, 第五十名 1 分), 同分时按播放记录中的收听量排序, 之后追加只在播放记录中出现的专辑, 最多 50 个
+func (c *Client) deriveTopAlbums(dbc dbClient, term topTerm, tn time.Time) ([]string, error) {
+	tracksKey := topSnapshotKey(term.name, ChartEntityTracks)
+
+	date, err := dbc.GetMapStr("updated-times", tracksKey)
+	if err != nil {
+		return nil, err
+	}
+
+	trackIDs, err := getTopSnapshotIDs(dbc, tracksKey, date)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[string]int{}
+
+	for i, trackID := range trackIDs {
+		track, err := c.getTrackCache(dbc, trackID)
+		if err != nil {
+			return nil, err
+		}
+
+		if track == nil {
+			continue
+		}
+
+		scores[track.Album.ID] += len(trackIDs) - i
+	}
+
+	historyCounts := map[string]int{}
+
+	charts, err := c.computeCharts(dbc, term.lookback(tn), tn, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, top := range charts[ChartEntityAlbums] {
+		historyCounts[top.ID] = top.Count
+	}
+
+	var albums []string
+
+	for id := range scores {
+		albums = append(albums, id)
+	}
+
+	slices.SortStableFunc(albums, func(a, b string) int {
+		if scores[a] != scores[b] {
+			return scores[b] - scores[a]
+		}
+		return historyCounts[b] - historyCounts[a]
+	})
+
+	for _, top := range charts[ChartEntityAlbums] {
+		if len(albums) >= 50 {
+			break
+		}
+
+		if scores[top.ID] == 0 {
+			albums = append(albums, top.ID)
+		}
+	}
+
+	if len(albums) > 50 {
+		albums = albums[:50]
+	}
+
+	return albums, nil
+}
@@ -1,497 +1,145 @@
 package spotify
 
 import (
-	"encoding/json"
-	"log/slog"
 	"sort"
-	"strings"
 	"time"
-
-	"github.com/zmb3/spotify/v2"
 )
 
-// TODO: DailyTrack, Album
-
-func (c *Client) saveTopArtists(dbc dbClient) error {
-	m, err := dbc.GetMapStr("updated-times", "monthly-top-artists")
-	if err != nil {
-		return err
+// getSpotifyTopIDs 返回 Spotify 排行榜快照中的 ID, date 为零值时返回最新的快照, 若快照不存在会返回 nil
+func getSpotifyTopIDs(dbc dbClient, term, entity string, date time.Time) ([]string, error) {
+	if !isValidTopSnapshot(term, entity) {
+		return nil, errInvalidTopSnapshot
 	}
 
-	h, err := dbc.GetMapStr("updated-times", "half-yearly-top-artists")
+	d, err := getTopSnapshotDate(dbc, term, entity, date)
 	if err != nil {
-		return err
-	}
-
-	y, err := dbc.GetMapStr("updated-times", "yearly-top-artists")
-	if err != nil {
-		return err
+		return nil, err
 	}
 
-	t := time.Time{}
+	return getTopSnapshotIDs(dbc, topSnapshotKey(term, entity), d)
+}
 
-	if m != "" {
-		t, err = time.Parse(time.DateOnly, m)
-		if err != nil {
-			return err
-		}
+// GetSpotifyTopArtists 返回 Spotify 艺术家榜, term 应使用 TermMonthly 等, date 为零值时返回最新的快照, 若快照不存在会返回 nil
+func (c *Client) GetSpotifyTopArtists(dbc dbClient, term string, date time.Time) ([]Artist, error) {
+	ids, err := getSpotifyTopIDs(dbc, term, ChartEntityArtists, date)
+	if err != nil {
+		return nil, err
 	}
 
-	tn := time.Now()
-	tns := tn.Format(time.DateOnly)
-
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || m == "" {
-		slog.Debug("正在更新 Spotify 艺术家月榜")
-
-		fap, err := c.C.CurrentUsersTopArtists(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.ShortTermRange))
-		if err != nil {
-			return err
-		}
-
-		var artists []string
-
-		for _, artist := range fap.Artists {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", artist.ID.String())
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				err = saveID(dbc, artist.ID.String(), c.convertArtist(&artist).toMap())
-				if err != nil {
-					return err
-				}
-			}
-			artists = append(artists, artist.ID.String())
-		}
-
-		j, err := json.Marshal(artists)
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("monthly-top-artists", tns, string(j))
-		if err != nil {
-			return err
-		}
+	var artists []Artist
 
-		err = dbc.SetMap("updated-times", "monthly-top-artists", tns)
+	for _, id := range ids {
+		artist, err := c.getArtistCache(dbc, id)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		slog.Debug("Spotify 艺术家月榜更新成功")
-	}
-
-	if h != "" {
-		t, err = time.Parse(time.DateOnly, h)
-		if err != nil {
-			return err
-		}
+		artists = append(artists, *artist)
 	}
 
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || h == "" {
-		slog.Debug("正在更新 Spotify 艺术家半年榜")
-
-		fap, err := c.C.CurrentUsersTopArtists(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.MediumTermRange))
-		if err != nil {
-			return err
-		}
-
-		var artists []string
-
-		for _, artist := range fap.Artists {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", artist.ID.String())
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				err = saveID(dbc, artist.ID.String(), c.convertArtist(&artist).toMap())
-				if err != nil {
-					return err
-				}
-			}
-			artists = append(artists, artist.ID.String())
-		}
-
-		j, err := json.Marshal(artists)
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("half-yearly-top-artists", tns, string(j))
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("updated-times", "half-yearly-top-artists", tns)
-		if err != nil {
-			return err
-		}
-
-		slog.Debug("Spotify 艺术家半年榜更新成功")
-	}
+	return artists, nil
+}
 
-	if y != "" {
-		t, err = time.Parse(time.DateOnly, y)
-		if err != nil {
-			return err
-		}
+// GetSpotifyTopTracks 返回 Spotify 曲目榜, term 应使用 TermMonthly 等, date 为零值时返回最新的快照, 若快照不存在会返回 nil
+func (c *Client) GetSpotifyTopTracks(dbc dbClient, term string, date time.Time) ([]Track, error) {
+	ids, err := getSpotifyTopIDs(dbc, term, ChartEntityTracks, date)
+	if err != nil {
+		return nil, err
 	}
 
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || y == "" {
-		slog.Debug("正在更新 Spotify 艺术家年榜")
+	var tracks []Track
 
-		fap, err := c.C.CurrentUsersTopArtists(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.LongTermRange))
+	for _, id := range ids {
+		track, err := c.getTrackCache(dbc, id)
 		if err != nil {
-			return err
-		}
-
-		var artists []string
-
-		for _, artist := range fap.Artists {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", artist.ID.String())
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				err = saveID(dbc, artist.ID.String(), c.convertArtist(&artist).toMap())
-				if err != nil {
-					return err
-				}
-			}
-			artists = append(artists, artist.ID.String())
-		}
-
-		j, err := json.Marshal(artists)
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("yearly-top-artists", tns, string(j))
-		if err != nil {
-			return err
+			return nil, err
 		}
 
-		err = dbc.SetMap("updated-times", "yearly-top-artists", tns)
-		if err != nil {
-			return err
+		if track == nil {
+			continue
 		}
 
-		slog.Debug("Spotify 艺术家年榜更新成功")
+		tracks = append(tracks, *track)
 	}
 
-	return nil
+	return tracks, nil
 }
 
-func (c *Client) correctTrack(dbc dbClient, track spotify.FullTrack, timeNow time.Time, lookbackDuration time.Time) (*Track, error) {
-	searchResults, err := c.C.Search(c.Ctx, "artist:"+track.Artists[0].Name+" track:"+track.Name, spotify.SearchTypeTrack)
-	if err != nil {
-		return nil, err
-	}
-
-	var correctSearchResults []spotify.FullTrack
-
-	for _, searchResult := range searchResults.Tracks.Tracks {
-		if strings.HasPrefix(track.Name, searchResult.Name) && searchResult.Artists[0].ID.String() == track.Artists[0].ID.String() || strings.HasPrefix(searchResult.Name, track.Name) && searchResult.Artists[0].ID.String() == track.Artists[0].ID.String() {
-			correctSearchResults = append(correctSearchResults, searchResult)
-		}
-	}
-
-	tops, err := c.GetTopTracksIDs(dbc, lookbackDuration, timeNow, 50)
+// GetSpotifyTopAlbums 返回由 Spotify 曲目榜推导的专辑榜, term 应使用 TermMonthly 等, date 为零值时返回最新的快照, 若快照不存在会返回 nil
+func (c *Client) GetSpotifyTopAlbums(dbc dbClient, term string, date time.Time) ([]Album, error) {
+	ids, err := getSpotifyTopIDs(dbc, term, ChartEntityAlbums, date)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, top := range tops {
-		for _, correctSearchResult := range correctSearchResults {
-			if top.ID == correctSearchResult.ID.String() {
-				return c.getTrackCache(dbc, top.ID)
-			}
-		}
-	}
+	var albums []Album
 
-	// 按照是否点赞补救
-	for _, v := range correctSearchResults {
-		isSaved, err := c.C.UserHasTracks(c.Ctx, v.ID)
+	for _, id := range ids {
+		album, err := c.getAlbumCache(dbc, id)
 		if err != nil {
 			return nil, err
 		}
 
-		if isSaved[0] {
-			return c.convertTrack(dbc, &v)
-		}
+		albums = append(albums, *album)
 	}
 
-	return nil, nil
+	return albums, nil
 }
 
-func (c *Client) saveTopTracks(dbc dbClient) error {
-	m, err := dbc.GetMapStr("updated-times", "monthly-top-tracks")
-	if err != nil {
-		return err
-	}
-
-	h, err := dbc.GetMapStr("updated-times", "half-yearly-top-tracks")
-	if err != nil {
-		return err
+// GetSpotifyTopDates 返回已存储的 Spotify 排行榜快照的日期, 按时间排序, entity 应使用 ChartEntityTracks 等
+func (c *Client) GetSpotifyTopDates(dbc dbClient, term, entity string) ([]string, error) {
+	if !isValidTopSnapshot(term, entity) {
+		return nil, errInvalidTopSnapshot
 	}
 
-	y, err := dbc.GetMapStr("updated-times", "yearly-top-tracks")
-	if err != nil {
-		return err
-	}
-
-	t := time.Time{}
-
-	tn := time.Now()
-	tns := tn.Format(time.DateOnly)
-
-	if m != "" {
-		t, err = time.Parse(time.DateOnly, m)
-		if err != nil {
-			return err
-		}
-	}
-
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || m == "" {
-		slog.Debug("正在更新 Spotify 曲目月榜")
-
-		ftp, err := c.C.CurrentUsersTopTracks(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.ShortTermRange))
-		if err != nil {
-			return err
-		}
-
-		var tracks []string
-
-		for _, track := range ftp.Tracks {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", track.ID.String())
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				converted, err := c.convertTrack(dbc, &track)
-				if err != nil {
-					return err
-				}
-
-				err = saveID(dbc, track.ID.String(), converted.toMap())
-				if err != nil {
-					return err
-				}
-			}
-
-			// Spotify 的一个 Bug: 有的曲目会被重置版混音版的同名曲目顶替, 排行榜上统计会混淆
-			saved, err := c.C.UserHasTracks(c.Ctx, track.ID)
-			if err != nil {
-				return err
-			}
-
-			if !saved[0] {
-				slog.Info("此歌曲未点赞, 可能是 Spotify 的 Bug, 进行补救", "名称", track.Name, "ID", track.ID, "艺术家", track.Artists[0].Name)
-
-				ct, err := c.correctTrack(dbc, track, tn, tn.AddDate(0, -1, 0))
-				if err != nil {
-					return err
-				}
-
-				if ct != nil {
-					slog.Info("补救成功, 替换为", "名称", ct.Name, "ID", ct.ID, "艺术家", ct.Artists[0].Name)
-					tracks = append(tracks, ct.ID)
-				} else {
-					slog.Info("补救失败, 未替换")
-				}
-
-			} else {
-				tracks = append(tracks, track.ID.String())
-			}
-		}
-
-		j, err := json.Marshal(tracks)
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("monthly-top-tracks", tns, string(j))
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("updated-times", "monthly-top-tracks", tns)
-		if err != nil {
-			return err
-		}
-
-		slog.Debug("Spotify 曲目月榜更新成功")
-	}
-
-	if h != "" {
-		t, err = time.Parse(time.DateOnly, h)
-		if err != nil {
-			return err
-		}
-	}
-
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || h == "" {
-		slog.Debug("正在更新 Spotify 曲目半年榜")
-
-		ftp, err := c.C.CurrentUsersTopTracks(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.MediumTermRange))
-		if err != nil {
-			return err
-		}
-
-		var tracks []string
-
-		for _, track := range ftp.Tracks {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", track.ID.String())
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				converted, err := c.convertTrack(dbc, &track)
-				if err != nil {
-					return err
-				}
-
-				err = saveID(dbc, track.ID.String(), converted.toMap())
-				if err != nil {
-					return err
-				}
-			}
-
-			saved, err := c.C.UserHasTracks(c.Ctx, track.ID)
-			if err != nil {
-				return err
-			}
-
-			if !saved[0] {
-				slog.Info("此歌曲未点赞, 可能是 Spotify 的 Bug, 进行补救", "名称", track.Name, "ID", track.ID, "艺术家", track.Artists[0].Name)
-
-				ct, err := c.correctTrack(dbc, track, tn, tn.AddDate(0, -6, 0))
-				if err != nil {
-					return err
-				}
-
-				if ct != nil {
-					slog.Info("补救成功, 替换为", "名称", ct.Name, "ID", ct.ID, "艺术家", ct.Artists[0].Name)
-					tracks = append(tracks, ct.ID)
-				} else {
-					slog.Info("补救失败, 未替换")
-				}
-
-			} else {
-				tracks = append(tracks, track.ID.String())
-			}
-
-		}
-
-		j, err := json.Marshal(tracks)
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("half-yearly-top-tracks", tns, string(j))
-		if err != nil {
-			return err
-		}
+	return getSnapshotDates(dbc, topSnapshotKey(term, entity))
+}
 
-		err = dbc.SetMap("updated-times", "half-yearly-top-tracks", tns)
-		if err != nil {
-			return err
-		}
+type Tops struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
 
-		slog.Debug("Spotify 曲目半年榜更新成功")
-	}
+// TopItem 是 GetTops 与 /tops 的响应中的一项, Item 为 *Track *Artist 或 *Album, 若信息不存在为 null
+type TopItem struct {
+	Tops
+	Item any `json:"item"`
+}
 
-	if y != "" {
-		t, err = time.Parse(time.DateOnly, y)
-		if err != nil {
-			return err
-		}
+// GetTops 返回一段时间内(包括t1和t2)的热门曲目 艺术家或专辑与其信息, entity 应使用 ChartEntityTracks 等, 若整段时间都没有数据会返回nil
+func (c *Client) GetTops(dbc dbClient, entity string, t1, t2 time.Time, limit int) ([]TopItem, error) {
+	charts, err := c.computeCharts(dbc, t1, t2, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	if int(tn.Month()) > int(t.Month()) || tn.Year() > t.Year() || y == "" {
-		slog.Debug("正在更新 Spotify 曲目年榜")
+	var items []TopItem
 
-		ftp, err := c.C.CurrentUsersTopTracks(c.Ctx, spotify.Limit(50), spotify.Timerange(spotify.LongTermRange))
-		if err != nil {
-			return err
-		}
+	for _, top := range charts[entity] {
+		item := TopItem{Tops: top}
 
-		var tracks []string
-
-		for _, track := range ftp.Tracks {
-			exists, err := dbc.CheckIfMapFieldExists("spotify-ids", track.ID.String())
+		switch entity {
+		case ChartEntityTracks:
+			track, err := c.getTrackCache(dbc, top.ID)
 			if err != nil {
-				return err
+				return nil, err
 			}
-
-			if !exists {
-				converted, err := c.convertTrack(dbc, &track)
-				if err != nil {
-					return err
-				}
-
-				err = saveID(dbc, track.ID.String(), converted.toMap())
-				if err != nil {
-					return err
-				}
+			if track != nil {
+				item.Item = track
 			}
-
-			saved, err := c.C.UserHasTracks(c.Ctx, track.ID)
-			if err != nil {
-				return err
-			}
-
-			if !saved[0] {
-				slog.Info("此歌曲未点赞, 可能是 Spotify 的 Bug, 进行补救", "名称", track.Name, "ID", track.ID, "艺术家", track.Artists[0].Name)
-
-				ct, err := c.correctTrack(dbc, track, tn, tn.AddDate(-1, 0, 0))
-				if err != nil {
-					return err
-				}
-
-				if ct != nil {
-					slog.Info("补救成功, 替换为", "名称", ct.Name, "ID", ct.ID, "艺术家", ct.Artists[0].Name)
-					tracks = append(tracks, ct.ID)
-				} else {
-					slog.Info("补救失败, 未替换")
-				}
-
-			} else {
-				tracks = append(tracks, track.ID.String())
-			}
-
+		case ChartEntityArtists:
+			item.Item, err = c.getArtistCache(dbc, top.ID)
+		case ChartEntityAlbums:
+			item.Item, err = c.getAlbumCache(dbc, top.ID)
 		}
-
-		j, err := json.Marshal(tracks)
 		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("yearly-top-tracks", tns, string(j))
-		if err != nil {
-			return err
-		}
-
-		err = dbc.SetMap("updated-times", "yearly-top-tracks", tns)
-		if err != nil {
-			return err
+			return nil, err
 		}
 
-		slog.Debug("Spotify 曲目年榜更新成功")
+		items = append(items, item)
 	}
 
-	return nil
-}
-
-type Tops struct {
-	ID    string `json:"id"`
-	Count int    `json:"count"`
+	return items, nil
 }
 
 // GetTopTracksIDs TODO: 算法需要增强
@@ -627,3 +275,22 @@ func (c *Client) GetTopAlbumsIDs(dbc dbClient, t1, t2 time.Time, limit int) ([]T
 
 	return tops, nil
 }
+
+// sortTops 按收听量降序排列, limit为0则不限制, counts 为空会返回 nil
+func sortTops(counts map[string]int, limit int) []Tops {
+	var tops []Tops
+
+	for id, count := range counts {
+		tops = append(tops, Tops{id, count})
+	}
+
+	sort.Slice(tops, func(i, j int) bool {
+		return tops[i].Count > tops[j].Count
+	})
+
+	if limit > 0 && len(tops) > limit {
+		tops = tops[:limit]
+	}
+
+	return tops
+}
@@ -0,0 +1,245 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetupTracing 的 exporter
+const (
+	TracingOTLP   = "otlp"   // OTLP/HTTP, 地址等通过 OTEL_EXPORTER_OTLP_ENDPOINT 等环境变量设置, 如本地的 http://localhost:4318
+	TracingStdout = "stdout" // 以 JSON 输出到标准输出
+)
+
+var errInvalidTracingExporter = errors.New("无效的 OpenTelemetry exporter")
+
+// tracer 使用全局的 TracerProvider, 未调用 SetupTracing 时不会记录任何 span
+var tracer = otel.Tracer("github.com/HenTaku321/spotify-insights-go")
+
+// SetupTracing 设置全局的 OpenTelemetry TracerProvider, 之后定时任务 Spotify 请求与 TraceDB 包装的数据库操作会记录 span
+// 程序退出前应调用返回的 shutdown, 以发送尚未发送的 span
+func SetupTracing(ctx context.Context, exporter string) (shutdown func(context.Context) error, err error) {
+	var exp sdktrace.SpanExporter
+
+	switch exporter {
+	case TracingOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case TracingStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, errInvalidTracingExporter
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "spotify-insights")))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// doJob 运行一次定时任务, 记录指标与 span, 使用 TraceDB 时任务中的数据库操作与 Spotify 请求会归入这个 span
+func (c *Client) doJob(dbc dbClient, job string, f func(dbClient) error) error {
+	ctx, span := tracer.Start(c.Ctx, "job "+job, trace.WithAttributes(attribute.String("job", job)))
+	start := time.Now()
+
+	err := f(withTraceContext(dbc, ctx))
+
+	observeJob(job, start, err)
+	endSpan(span, err)
+
+	return err
+}
+
+// ctx 返回请求 Spotify 时使用的 Context, dbc 带有所在任务的 span 时使用其 Context
+func (c *Client) ctx(dbc dbClient) context.Context {
+	if t, ok := dbc.(*tracedDB); ok && t.ctx != nil {
+		return t.ctx
+	}
+	return c.Ctx
+}
+
+// tracingTransport 为每个 Spotify 请求记录一个 span
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(r.Context(), "spotify "+spotifyEndpoint(r),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.full", r.URL.String()),
+		),
+	)
+
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+
+	spanErr := err
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+		if resp.StatusCode >= 400 {
+			spanErr = errors.New("Spotify 返回 " + resp.Status)
+		}
+	}
+
+	endSpan(span, spanErr)
+
+	return resp, err
+}
+
+// TraceDB 包装 dbc, 为每个数据库操作记录一个 span, 应在 Run 等之前使用, 如 sc.Run(spotify.TraceDB(vc))
+func TraceDB(dbc dbClient) dbClient {
+	if _, ok := dbc.(*tracedDB); ok {
+		return dbc
+	}
+	return &tracedDB{next: dbc}
+}
+
+type tracedDB struct {
+	next dbClient
+	ctx  context.Context // 所在任务的 Context, 为 nil 时 span 没有父 span
+}
+
+// withTraceContext 若 dbc 为 TraceDB 的返回值, 返回以 ctx 为父 span 的副本
+func withTraceContext(dbc dbClient, ctx context.Context) dbClient {
+	if t, ok := dbc.(*tracedDB); ok {
+		return &tracedDB{next: t.next, ctx: ctx}
+	}
+	return dbc
+}
+
+func (t *tracedDB) start(op, key string) trace.Span {
+	ctx := t.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, span := tracer.Start(ctx, "db "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.operation.name", op),
+			attribute.String("db.key", key),
+		),
+	)
+
+	return span
+}
+
+func (t *tracedDB) SetString(key string, value string, ex *time.Duration) error {
+	span := t.start("SetString", key)
+	err := t.next.SetString(key, value, ex)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedDB) GetString(key string) (string, error) {
+	span := t.start("GetString", key)
+	s, err := t.next.GetString(key)
+	endSpan(span, err)
+	return s, err
+}
+
+func (t *tracedDB) SetMap(key, field, value string) error {
+	span := t.start("SetMap", key)
+	err := t.next.SetMap(key, field, value)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedDB) GetMapStr(key, field string) (string, error) {
+	span := t.start("GetMapStr", key)
+	s, err := t.next.GetMapStr(key, field)
+	endSpan(span, err)
+	return s, err
+}
+
+func (t *tracedDB) GetMapInt64(key, field string) (int64, error) {
+	span := t.start("GetMapInt64", key)
+	n, err := t.next.GetMapInt64(key, field)
+	endSpan(span, err)
+	return n, err
+}
+
+func (t *tracedDB) GetMapLen(key string) (int64, error) {
+	span := t.start("GetMapLen", key)
+	n, err := t.next.GetMapLen(key)
+	endSpan(span, err)
+	return n, err
+}
+
+func (t *tracedDB) GetMapAll(key string) (map[string]string, error) {
+	span := t.start("GetMapAll", key)
+	m, err := t.next.GetMapAll(key)
+	endSpan(span, err)
+	return m, err
+}
+
+func (t *tracedDB) CheckIfMapFieldExists(key, field string) (bool, error) {
+	span := t.start("CheckIfMapFieldExists", key)
+	exists, err := t.next.CheckIfMapFieldExists(key, field)
+	endSpan(span, err)
+	return exists, err
+}
+
+func (t *tracedDB) AppendSlice(key string, value []string) error {
+	span := t.start("AppendSlice", key)
+	err := t.next.AppendSlice(key, value)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedDB) GetSlice(key string, start, stop int64) ([]string, error) {
+	span := t.start("GetSlice", key)
+	sli, err := t.next.GetSlice(key, start, stop)
+	endSpan(span, err)
+	return sli, err
+}
+
+func (t *tracedDB) GetSliceByIndex(key string, index int64) (string, error) {
+	span := t.start("GetSliceByIndex", key)
+	s, err := t.next.GetSliceByIndex(key, index)
+	endSpan(span, err)
+	return s, err
+}
+
+func (t *tracedDB) GetSliceLen(key string) (int64, error) {
+	span := t.start("GetSliceLen", key)
+	n, err := t.next.GetSliceLen(key)
+	endSpan(span, err)
+	return n, err
+}
+
+func (t *tracedDB) Delete(key string) error {
+	span := t.start("Delete", key)
+	err := t.next.Delete(key)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,391 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Spotify 的一个 Bug: 曲目榜上有的曲目会被重制版混音版的同名曲目顶替, 未点赞的曲目会按 Client.CorrectionStrategies 依次尝试找回被顶替的曲目
+// 每次替换记录在数据库字典 {时间范围}-top-tracks-corrections 中, 以快照日期为键, []TrackCorrection 的 JSON 为值
+const (
+	CorrectionISRC    = "isrc"    // ISRC 相同的其它曲目中收听量最多的
+	CorrectionSong    = "song"    // 同一首歌曲(见 GetSongID)的其它版本中收听量最多的
+	CorrectionHistory = "history" // 按名称与第一位艺术家搜索到的曲目中收听量最多的
+	CorrectionSaved   = "saved"   // 按名称与第一位艺术家搜索到的曲目中第一个已点赞的
+)
+
+// defaultCorrectionStrategies 在 Client.CorrectionStrategies 为空时使用
+var defaultCorrectionStrategies = []string{CorrectionISRC, CorrectionSong, CorrectionHistory, CorrectionSaved}
+
+var errCorrectionNotFound = errors.New("替换记录不存在或已还原")
+
+// correctionInput 是补救时的输入, counts 为时间范围内各曲目的收听量
+type correctionInput struct {
+	track  spotify.FullTrack
+	counts map[string]int
+}
+
+type correctionStrategy func(c *Client, dbc dbClient, in correctionInput) (string, error)
+
+var correctionStrategies = map[string]correctionStrategy{
+	CorrectionISRC:    (*Client).correctByISRC,
+	CorrectionSong:    (*Client).correctBySong,
+	CorrectionHistory: (*Client).correctByHistory,
+	CorrectionSaved:   (*Client).correctBySaved,
+}
+
+// TrackCorrection 是曲目榜快照中的一次替换, Rank 从 1 开始
+type TrackCorrection struct {
+	Rank          int    `json:"rank"`
+	OriginalID    string `json:"original_id"`
+	ReplacementID string `json:"replacement_id"`
+	Strategy      string `json:"strategy"`
+	CorrectedAt   string `json:"corrected_at"`
+	Reverted      bool   `json:"reverted,omitempty"`
+}
+
+func correctionsKey(term string) string {
+	return topSnapshotKey(term, ChartEntityTracks) + "-corrections"
+}
+
+// correctTrack 按策略依次尝试找回被顶替的曲目, counts 见 getTrackCounts, 返回曲目 ID 与使用的策略, 均失败时返回空字符串
+func (c *Client) correctTrack(dbc dbClient, track spotify.FullTrack, counts map[string]int) (string, string, error) {
+	strategies := c.CorrectionStrategies
+	if len(strategies) == 0 {
+		strategies = defaultCorrectionStrategies
+	}
+
+	in := correctionInput{track: track, counts: counts}
+
+	for _, name := range strategies {
+		strategy, ok := correctionStrategies[name]
+		if !ok {
+			slog.Warn("未知的补救策略, 已跳过", "策略", name)
+			continue
+		}
+
+		id, err := strategy(c, dbc, in)
+		if err != nil {
+			return "", "", err
+		}
+
+		if id != "" && id != track.ID.String() {
+			return id, name, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// getTrackCounts 返回一段时间内各曲目的收听量, 同一时间范围的补救共用
+func (c *Client) getTrackCounts(dbc dbClient, t1, t2 time.Time) (map[string]int, error) {
+	charts, err := c.computeCharts(dbc, t1, t2, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+
+	for _, top := range charts[ChartEntityTracks] {
+		counts[top.ID] = top.Count
+	}
+
+	return counts, nil
+}
+
+// mostPlayed 返回 ids 中收听量最多的, 均未收听过时返回空字符串
+// 被顶替时原曲目本身也可能有收听量, 不参与比较
+func (in correctionInput) mostPlayed(ids []string) string {
+	best := ""
+
+	for _, id := range ids {
+		if id != in.track.ID.String() && in.counts[id] > in.counts[best] {
+			best = id
+		}
+	}
+
+	return best
+}
+
+// searchTrackIDs 搜索曲目并缓存结果, 返回满足 match 的曲目 ID
+func (c *Client) searchTrackIDs(dbc dbClient, query string, match func(spotify.FullTrack) bool) ([]string, error) {
+	searchResults, err := c.C.Search(c.ctx(dbc), query, spotify.SearchTypeTrack)
+	if err != nil {
+		return nil, err
+	}
+
+	if searchResults.Tracks == nil {
+		return nil, nil
+	}
+
+	var ids []string
+
+	for _, searchResult := range searchResults.Tracks.Tracks {
+		if !match(searchResult) {
+			continue
+		}
+
+		exists, err := dbc.CheckIfMapFieldExists("spotify-ids", searchResult.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			converted, err := c.convertTrack(dbc, &searchResult)
+			if err != nil {
+				return nil, err
+			}
+
+			err = saveID(dbc, searchResult.ID.String(), converted.toMap())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ids = append(ids, searchResult.ID.String())
+	}
+
+	return ids, nil
+}
+
+// searchSameNameTrackIDs 按名称与第一位艺术家搜索, 名称互为前缀的视为同名曲目
+func (c *Client) searchSameNameTrackIDs(dbc dbClient, track spotify.FullTrack) ([]string, error) {
+	return c.searchTrackIDs(dbc, "artist:"+track.Artists[0].Name+" track:"+track.Name, func(searchResult spotify.FullTrack) bool {
+		if searchResult.Artists[0].ID != track.Artists[0].ID {
+			return false
+		}
+		return strings.HasPrefix(track.Name, searchResult.Name) || strings.HasPrefix(searchResult.Name, track.Name)
+	})
+}
+
+func (c *Client) correctByISRC(dbc dbClient, in correctionInput) (string, error) {
+	isrc := in.track.ExternalIDs["isrc"]
+	if isrc == "" {
+		return "", nil
+	}
+
+	ids, err := c.searchTrackIDs(dbc, "isrc:"+isrc, func(searchResult spotify.FullTrack) bool {
+		return strings.EqualFold(searchResult.ExternalIDs["isrc"], isrc)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return in.mostPlayed(ids), nil
+}
+
+func (c *Client) correctBySong(dbc dbClient, in correctionInput) (string, error) {
+	songID, err := c.GetSongID(dbc, in.track.ID.String())
+	if err != nil {
+		return "", err
+	}
+
+	if songID == "" {
+		return "", nil
+	}
+
+	versions, err := c.GetSongVersionsIDs(dbc, songID)
+	if err != nil {
+		return "", err
+	}
+
+	return in.mostPlayed(versions), nil
+}
+
+func (c *Client) correctByHistory(dbc dbClient, in correctionInput) (string, error) {
+	ids, err := c.searchSameNameTrackIDs(dbc, in.track)
+	if err != nil {
+		return "", err
+	}
+
+	return in.mostPlayed(ids), nil
+}
+
+func (c *Client) correctBySaved(dbc dbClient, in correctionInput) (string, error) {
+	ids, err := c.searchSameNameTrackIDs(dbc, in.track)
+	if err != nil {
+		return "", err
+	}
+
+	for _, id := range ids {
+		if id == in.track.ID.String() {
+			continue
+		}
+
+		isSaved, err := c.C.UserHasTracks(c.ctx(dbc), spotify.ID(id))
+		if err != nil {
+			return "", err
+		}
+
+		if isSaved[0] {
+			return id, nil
+		}
+	}
+
+	return "", nil
+}
+
+// saveTrackCorrections 存储一个快照的替换记录, 会覆盖同一快照之前的记录
+func saveTrackCorrections(dbc dbClient, term, date string, corrections []TrackCorrection) error {
+	j, err := json.Marshal(corrections)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap(correctionsKey(term), date, string(j))
+}
+
+func getTrackCorrections(dbc dbClient, term, date string) ([]TrackCorrection, error) {
+	s, err := dbc.GetMapStr(correctionsKey(term), date)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	var corrections []TrackCorrection
+
+	err = json.Unmarshal([]byte(s), &corrections)
+	if err != nil {
+		return nil, err
+	}
+
+	return corrections, nil
+}
+
+// getTopSnapshotDate date 为零值时返回最新的快照日期
+func getTopSnapshotDate(dbc dbClient, term, entity string, date time.Time) (string, error) {
+	if !date.IsZero() {
+		return date.Format(time.DateOnly), nil
+	}
+
+	return dbc.GetMapStr("updated-times", topSnapshotKey(term, entity))
+}
+
+// GetTrackCorrections 返回 Spotify 曲目榜快照中的替换记录, date 为零值时返回最新的快照的记录, 没有替换时返回 nil
+func (c *Client) GetTrackCorrections(dbc dbClient, term string, date time.Time) ([]TrackCorrection, error) {
+	if !slices.Contains(topTerms, term) {
+		return nil, errInvalidTopSnapshot
+	}
+
+	d, err := getTopSnapshotDate(dbc, term, ChartEntityTracks, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTrackCorrections(dbc, term, d)
+}
+
+// RevertTrackCorrection 将快照中的替换还原为 Spotify 返回的原曲目, date 为零值时使用最新的快照
+// 还原最新的快照后, 会重新推导同一日期的专辑榜
+func (c *Client) RevertTrackCorrection(dbc dbClient, term string, date time.Time, originalID string) error {
+	if !slices.Contains(topTerms, term) {
+		return errInvalidTopSnapshot
+	}
+
+	d, err := getTopSnapshotDate(dbc, term, ChartEntityTracks, date)
+	if err != nil {
+		return err
+	}
+
+	corrections, err := getTrackCorrections(dbc, term, d)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(corrections, func(correction TrackCorrection) bool {
+		return correction.OriginalID == originalID && !correction.Reverted
+	})
+	if i == -1 {
+		return errCorrectionNotFound
+	}
+
+	key := topSnapshotKey(term, ChartEntityTracks)
+
+	ids, err := getTopSnapshotIDs(dbc, key, d)
+	if err != nil {
+		return err
+	}
+
+	rank := corrections[i].Rank
+	if rank < 1 || rank > len(ids) || ids[rank-1] != corrections[i].ReplacementID {
+		rank = slices.Index(ids, corrections[i].ReplacementID) + 1
+	}
+
+	if rank == 0 {
+		return errCorrectionNotFound
+	}
+
+	ids[rank-1] = originalID
+
+	j, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	err = dbc.SetMap(key, d, string(j))
+	if err != nil {
+		return err
+	}
+
+	corrections[i].Reverted = true
+
+	err = saveTrackCorrections(dbc, term, d, corrections)
+	if err != nil {
+		return err
+	}
+
+	latest, err := dbc.GetMapStr("updated-times", key)
+	if err != nil {
+		return err
+	}
+
+	if latest == d {
+		err = c.rederiveTopAlbums(dbc, term, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Info("已还原曲目榜中的替换", "时间范围", term, "日期", d, "原曲目", originalID, "替换曲目", corrections[i].ReplacementID)
+
+	return nil
+}
+
+// rederiveTopAlbums 按最新的曲目榜快照重新推导专辑榜, 覆盖同一日期的专辑榜, 专辑榜尚未推导时不做处理
+func (c *Client) rederiveTopAlbums(dbc dbClient, term, date string) error {
+	i := slices.IndexFunc(topSnapshotTerms, func(t topTerm) bool {
+		return t.name == term
+	})
+
+	albumsKey := topSnapshotKey(term, ChartEntityAlbums)
+
+	last, err := dbc.GetMapStr("updated-times", albumsKey)
+	if err != nil {
+		return err
+	}
+
+	if last != date {
+		return nil
+	}
+
+	albums, err := c.deriveTopAlbums(dbc, topSnapshotTerms[i], time.Now())
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(albums)
+	if err != nil {
+		return err
+	}
+
+	return dbc.SetMap(albumsKey, date, string(j))
+}
@@ -0,0 +1,515 @@
+package spotify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+//go:embed web/templates/*.html web/static
+var webFS embed.FS
+
+const (
+	webHistoryPageSize = 50
+	webDailyDays       = 30
+	webOverviewTops    = 5
+	webImageMinWidth   = 64
+	webChartWidth      = 720
+	webChartHeight     = 160
+)
+
+var webTemplateFuncs = template.FuncMap{
+	"mod": func(a, b int) int { return a % b },
+}
+
+// webTemplates 的键为页面名称, 每个页面与 layout.html 组成一个模板
+var webTemplates = map[string]*template.Template{}
+
+func init() {
+	for _, page := range []string{"overview", "tops", "history", "error"} {
+		webTemplates[page] = template.Must(template.New(page).Funcs(webTemplateFuncs).ParseFS(webFS, "web/templates/layout.html", "web/templates/"+page+".html"))
+	}
+}
+
+// webBar 是 SVG 柱状图中的一根柱, 坐标以 webChartWidth 与 webChartHeight 为画布
+type webBar struct {
+	Label  string
+	Count  int
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// webChart 是一个柱状图, LabelEvery 为每隔多少根柱显示一次标签
+type webChart struct {
+	Bars       []webBar
+	Max        int
+	LabelEvery int
+	Width      int
+	Height     int
+}
+
+func newWebChart(labels []string, counts []int, labelEvery int) webChart {
+	chart := webChart{LabelEvery: labelEvery, Width: webChartWidth, Height: webChartHeight}
+
+	for _, count := range counts {
+		chart.Max = max(chart.Max, count)
+	}
+
+	slot := float64(webChartWidth) / float64(len(counts))
+
+	for i, count := range counts {
+		h := 0.0
+		if chart.Max > 0 {
+			h = float64(count) / float64(chart.Max) * webChartHeight
+		}
+
+		chart.Bars = append(chart.Bars, webBar{
+			Label:  labels[i],
+			Count:  count,
+			X:      float64(i)*slot + slot*0.1,
+			Y:      webChartHeight - h,
+			Width:  slot * 0.8,
+			Height: h,
+		})
+	}
+
+	return chart
+}
+
+// webItem 是排行或播放记录中的一行, 数据库中缺少信息且无法请求 Spotify 时 Name 为 ID
+type webItem struct {
+	Rank     int
+	Count    int
+	PlayedAt string
+	ID       string
+	Name     string
+	By       string // 艺术家或播客名称
+	Album    string
+	ImageURL string
+	URL      string // Spotify 网页版的链接, 本地文件为空
+}
+
+type webOverview struct {
+	Total      int64
+	Today      int
+	Daily      webChart
+	Hourly     webChart
+	TopTracks  []webItem
+	TopArtists []webItem
+	Month      string
+}
+
+type webTops struct {
+	Entity string
+	From   string
+	To     string
+	Limit  int64
+	Items  []webItem
+}
+
+type webHistory struct {
+	Date    string
+	Page    int64
+	Pages   int64
+	Total   int64
+	Items   []webItem
+	PrevURL string
+	NextURL string
+}
+
+// webPage 是传给模板的数据, 页面均在仪表盘的根路径下, 模板中使用相对路径, 以便挂载在任意路径下
+type webPage struct {
+	Title string
+	Nav   string
+	Data  any
+}
+
+// DashboardHandler 返回内置的网页仪表盘, 包括每日与每小时收听量图表 带封面的热门排行与播放记录浏览, 可通过 http.StripPrefix 挂载在其它路径下
+// NewHandler 的 /dashboard/ 即为此仪表盘, 封面图片直接从 Spotify 的图片地址加载
+//
+//	GET /                                       概览
+//	GET /tops?entity=tracks&from=&to=&limit=50  热门曲目 艺术家或专辑, 默认为本月
+//	GET /history?page=1&date=                   播放记录, 从最新开始, date 为 time.DateOnly 格式时只显示当天
+//	GET /static/                                样式表等静态文件
+func (c *Client) DashboardHandler(dbc dbClient) http.Handler {
+	static, err := fs.Sub(webFS, "web")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/static/", http.FileServerFS(static))
+	mux.Handle("/tops", c.webHandler(dbc, "tops", "热门", c.serveWebTops))
+	mux.Handle("/history", c.webHandler(dbc, "history", "播放记录", c.serveWebHistory))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			renderWebError(w, r, &apiError{http.StatusNotFound, APIErrorNotFound, "页面不存在"})
+			return
+		}
+
+		c.webHandler(dbc, "overview", "概览", c.serveWebOverview).ServeHTTP(w, r)
+	})
+
+	return mux
+}
+
+type webFunc func(dbc dbClient, r *http.Request) (any, error)
+
+// webHandler 检查请求方法并渲染 page 对应的模板, 错误时渲染错误页面
+func (c *Client) webHandler(dbc dbClient, page, title string, f webFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			renderWebError(w, r, &apiError{http.StatusMethodNotAllowed, APIErrorMethodNotAllowed, "只接受 GET 与 HEAD 请求"})
+			return
+		}
+
+		data, err := f(dbc, r)
+		if err != nil {
+			renderWebError(w, r, err)
+			return
+		}
+
+		renderWebPage(w, r, http.StatusOK, page, webPage{Title: title, Nav: page, Data: data})
+	})
+}
+
+func renderWebPage(w http.ResponseWriter, r *http.Request, status int, page string, data webPage) {
+	var buf bytes.Buffer
+
+	err := webTemplates[page].ExecuteTemplate(&buf, "layout", data)
+	if err != nil {
+		slog.Warn("渲染页面失败", "页面", page, "error", err)
+		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		slog.Debug("写入响应失败", "error", err)
+	}
+}
+
+func renderWebError(w http.ResponseWriter, r *http.Request, err error) {
+	var ae *apiError
+	if !errors.As(err, &ae) {
+		slog.Warn("仪表盘请求处理失败", "error", err)
+		ae = &apiError{http.StatusInternalServerError, APIErrorInternal, "服务器内部错误"}
+	}
+
+	renderWebPage(w, r, ae.status, "error", webPage{Title: "错误", Data: ae.message})
+}
+
+func (c *Client) serveWebOverview(dbc dbClient, r *http.Request) (any, error) {
+	tn := time.Now()
+	overview := &webOverview{Month: tn.Format("2006-01")}
+
+	var err error
+
+	overview.Total, err = c.GetTotalPlaybackHistoryCount(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []string
+	var counts []int
+
+	for i := webDailyDays - 1; i >= 0; i-- {
+		day := tn.AddDate(0, 0, -i)
+
+		pr, err := c.GetPlaybackRangeOnADay(dbc, day)
+		if err != nil {
+			return nil, err
+		}
+
+		count := 0
+		if pr != nil {
+			count = pr.End - pr.Start + 1
+		}
+
+		labels = append(labels, day.Format("01-02"))
+		counts = append(counts, count)
+	}
+
+	overview.Today = counts[len(counts)-1]
+	overview.Daily = newWebChart(labels, counts, 5)
+
+	hourly, err := c.GetHourlyPlayBackCounts(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, counts = nil, nil
+	for hour := 0; hour < 24; hour++ {
+		labels = append(labels, strconv.Itoa(hour))
+		counts = append(counts, hourly[hour])
+	}
+
+	overview.Hourly = newWebChart(labels, counts, 3)
+
+	firstDay := time.Date(tn.Year(), tn.Month(), 1, 0, 0, 0, 0, tn.Location())
+
+	charts, err := c.webCharts(dbc, firstDay, tn, webOverviewTops)
+	if err != nil {
+		return nil, err
+	}
+
+	overview.TopTracks, err = c.webTopItems(dbc, ChartEntityTracks, charts[ChartEntityTracks])
+	if err != nil {
+		return nil, err
+	}
+
+	overview.TopArtists, err = c.webTopItems(dbc, ChartEntityArtists, charts[ChartEntityArtists])
+	if err != nil {
+		return nil, err
+	}
+
+	return overview, nil
+}
+
+func (c *Client) serveWebTops(dbc dbClient, r *http.Request) (any, error) {
+	q := r.URL.Query()
+
+	entity := q.Get("entity")
+	if entity == "" {
+		entity = ChartEntityTracks
+	}
+
+	if !slices.Contains(chartEntities, entity) {
+		return nil, invalidParameter("entity", "应为 tracks, artists 或 albums")
+	}
+
+	tn := time.Now()
+	t1 := time.Date(tn.Year(), tn.Month(), 1, 0, 0, 0, 0, time.Local)
+	t2 := time.Date(tn.Year(), tn.Month(), tn.Day(), 0, 0, 0, 0, time.Local)
+
+	if q.Get("from") != "" || q.Get("to") != "" {
+		var err error
+
+		t1, t2, err = parseDateRangeParams(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limit, err := parseIntParam(r, "limit", apiDefaultLimit, 1, apiMaxTopsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	charts, err := c.webCharts(dbc, t1, t2, int(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := c.webTopItems(dbc, entity, charts[entity])
+	if err != nil {
+		return nil, err
+	}
+
+	return &webTops{entity, t1.Format(time.DateOnly), t2.Format(time.DateOnly), limit, items}, nil
+}
+
+// webCharts 与 computeCharts 相同, 但只读模式下缺少曲目信息时返回空的排行
+func (c *Client) webCharts(dbc dbClient, t1, t2 time.Time, limit int) (map[string][]Tops, error) {
+	charts, err := c.computeCharts(dbc, t1, t2, limit)
+	if errors.Is(err, ErrNoSpotifyClient) {
+		slog.Debug("只读模式下缺少曲目信息, 无法统计排行", "error", err)
+		return nil, nil
+	}
+
+	return charts, err
+}
+
+// webTopItems 返回带有名称与封面的排行, 只读模式下数据库中缺少的信息以 ID 显示
+func (c *Client) webTopItems(dbc dbClient, entity string, tops []Tops) ([]webItem, error) {
+	var items []webItem
+	var err error
+
+	for i, top := range tops {
+		item := webItem{Rank: i + 1, Count: top.Count, ID: top.ID, Name: top.ID}
+
+		switch entity {
+		case ChartEntityTracks:
+			err = c.fillWebTrack(dbc, &item)
+		case ChartEntityArtists:
+			var artist *Artist
+
+			artist, err = c.getArtistCache(dbc, top.ID)
+			if artist != nil {
+				item.Name, item.ImageURL = artist.Name, webImageURL(artist.Images)
+				item.URL = "https://open.spotify.com/artist/" + url.PathEscape(top.ID)
+			}
+		case ChartEntityAlbums:
+			var album *Album
+
+			album, err = c.getAlbumCache(dbc, top.ID)
+			if album != nil {
+				item.Name, item.By, item.ImageURL = album.Name, joinArtistNames(album.Artists), webImageURL(album.Images)
+				item.URL = "https://open.spotify.com/album/" + url.PathEscape(top.ID)
+			}
+		}
+		if err != nil && !errors.Is(err, ErrNoSpotifyClient) {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (c *Client) fillWebTrack(dbc dbClient, item *webItem) error {
+	track, err := c.getTrackCache(dbc, item.ID)
+	if err != nil {
+		return err
+	}
+
+	if track == nil {
+		return nil
+	}
+
+	item.Name, item.By, item.Album = track.Name, joinArtistNames(track.Artists), track.Album.Name
+	item.ImageURL = webImageURL(track.Album.Images)
+
+	if !isLocalID(item.ID) {
+		item.URL = "https://open.spotify.com/track/" + url.PathEscape(item.ID)
+	}
+
+	return nil
+}
+
+func (c *Client) serveWebHistory(dbc dbClient, r *http.Request) (any, error) {
+	page, err := parseIntParam(r, "page", 1, 1, 1<<53)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := c.GetTotalPlaybackHistoryCount(dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	// first 与 last 为可浏览的播放记录的下标范围
+	first, last := int64(0), total-1
+	date := r.URL.Query().Get("date")
+
+	if date != "" {
+		t, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+		if err != nil {
+			return nil, invalidParameter("date", "日期格式应为 "+time.DateOnly)
+		}
+
+		pr, err := c.GetPlaybackRangeOnADay(dbc, t)
+		if err != nil {
+			return nil, err
+		}
+
+		first, last = 0, -1
+		if pr != nil {
+			first, last = int64(pr.Start), int64(pr.End)
+		}
+	}
+
+	count := last - first + 1
+	history := &webHistory{Date: date, Page: page, Total: count, Pages: max((count+webHistoryPageSize-1)/webHistoryPageSize, 1)}
+
+	// 从最新开始
+	stop := last - (page-1)*webHistoryPageSize
+	start := max(stop-webHistoryPageSize+1, first)
+
+	if stop >= first {
+		entries, err := getPlaybackEntries(dbc, start, stop)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range slices.Backward(entries) {
+			item := webItem{PlayedAt: entry.PlayedAt, ID: entry.ID, Name: entry.ID}
+
+			if entry.Type == EntryTypeEpisode {
+				var episode *Episode
+
+				episode, err = c.getEpisodeCache(dbc, entry.ID)
+				if episode != nil {
+					item.Name, item.By, item.ImageURL = episode.Name, episode.Show.Name, webImageURL(episode.Images)
+					item.URL = "https://open.spotify.com/episode/" + url.PathEscape(entry.ID)
+				}
+			} else {
+				err = c.fillWebTrack(dbc, &item)
+			}
+			if err != nil && !errors.Is(err, ErrNoSpotifyClient) {
+				return nil, err
+			}
+
+			history.Items = append(history.Items, item)
+		}
+	}
+
+	pageURL := func(p int64) string {
+		v := url.Values{"page": {strconv.FormatInt(p, 10)}}
+		if date != "" {
+			v.Set("date", date)
+		}
+		return "history?" + v.Encode()
+	}
+
+	if page > 1 {
+		history.PrevURL = pageURL(page - 1)
+	}
+	if page < history.Pages {
+		history.NextURL = pageURL(page + 1)
+	}
+
+	return history, nil
+}
+
+// webImageURL 返回宽度不小于 webImageMinWidth 的最小图片, 没有图片时返回空字符串
+func webImageURL(images []spotify.Image) string {
+	if len(images) == 0 {
+		return ""
+	}
+
+	sorted := slices.Clone(images)
+	slices.SortFunc(sorted, func(a, b spotify.Image) int {
+		return int(a.Width) - int(b.Width)
+	})
+
+	for _, image := range sorted {
+		if image.Width >= webImageMinWidth {
+			return image.URL
+		}
+	}
+
+	return sorted[len(sorted)-1].URL
+}
+
+func joinArtistNames(artists []Artist) string {
+	names := make([]string, len(artists))
+	for i, artist := range artists {
+		names[i] = artist.Name
+	}
+	return strings.Join(names, ", ")
+}
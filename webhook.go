@@ -0,0 +1,352 @@
+package spotify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Webhook 事件, 也是请求头 X-Spotify-Insights-Event 的值
+const (
+	WebhookNewPlays           = "new-plays"            // 保存了新的播放记录, data 为 {"plays": []PlayedTrack}
+	WebhookTopSnapshotUpdated = "top-snapshot-updated" // 更新了 Spotify 排行榜快照, data 为 {"term", "entity", "date", "ids"}
+	WebhookGapDetected        = "gap-detected"         // 检测到可能丢失播放记录的时间段, data 为 PlaybackGap
+	WebhookRepairPerformed    = "repair-performed"     // 修复或重新统计了收听量, data 为 {"kind"}
+)
+
+// WebhookRepairPerformed 的 kind
+const (
+	RepairDailyPlaybackRanges = "daily-playback-ranges" // 每日播放量统计不匹配时的自动修复
+	RepairAggregates          = "aggregates"            // RebuildAggregates
+)
+
+const (
+	webhookOutboxKey           = "webhook-outbox"
+	webhookMaxAttempts         = 10
+	webhookMaxBackoff          = time.Hour * 6
+	webhookRequestTimeout      = time.Second * 10
+	webhookDefaultPollInterval = time.Second * 30
+)
+
+// Webhook 是一个接收事件的地址, Events 为空时接收所有事件
+// Name 是发件箱中标识 Webhook 的名称, 为空时使用 URL, 同一 URL 配置多个 Webhook 时需要设置不同的 Name, 修改 Name 以外的配置后未发送的事件仍会按新的配置发送
+// Secret 不为空时, 请求头 X-Spotify-Insights-Signature 为 "sha256=" 加上以 Secret 为密钥对 "{X-Spotify-Insights-Timestamp}.{请求体}" 计算的 HMAC-SHA256 的十六进制
+type Webhook struct {
+	Name   string
+	URL    string
+	Secret string
+	Events []string
+}
+
+func (w *Webhook) name() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return w.URL
+}
+
+// checkWebhookNames 检查 Webhook 的名称是否重复, 名称重复时无法确定事件应使用的配置
+func checkWebhookNames(webhooks []Webhook) error {
+	names := map[string]bool{}
+
+	for _, webhook := range webhooks {
+		if names[webhook.name()] {
+			return errors.New("Webhook 名称重复, 同一 URL 配置多个 Webhook 时需要设置不同的 Name: " + webhook.name())
+		}
+
+		names[webhook.name()] = true
+	}
+
+	return nil
+}
+
+// WebhookPayload 是请求体
+type WebhookPayload struct {
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	CreatedAt string `json:"created_at"`
+	Data      any    `json:"data"`
+}
+
+// webhookDelivery 是数据库字典 webhook-outbox 中的存储格式, 以 ID 为键, 发送成功或超过重试次数后值改为空字符串, 全部完成后才删除整个发件箱
+// 不存储 Secret, 发送时按 Webhook 的名称从 Client.Webhooks 中查找, 旧的事件没有名称, 以 URL 作为名称
+type webhookDelivery struct {
+	ID            string          `json:"id"`
+	Webhook       string          `json:"webhook"`
+	URL           string          `json:"url"`
+	Event         string          `json:"event"`
+	Body          json.RawMessage `json:"body"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt string          `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+// emitWebhook 为每个接收该事件的 Webhook 在发件箱中添加一次发送, 由 RunWebhookDispatcher 发送
+// 失败只记录日志, 不影响调用方
+func (c *Client) emitWebhook(dbc dbClient, event string, data any) {
+	if len(c.Webhooks) == 0 {
+		return
+	}
+
+	err := c.enqueueWebhook(dbc, event, data)
+	if err != nil {
+		slog.Warn("添加 Webhook 事件失败", "事件", event, "error", err)
+	}
+}
+
+func (c *Client) enqueueWebhook(dbc dbClient, event string, data any) error {
+	tn := time.Now()
+
+	id, err := newWebhookDeliveryID()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(WebhookPayload{id, event, tn.Format(time.RFC3339), data})
+	if err != nil {
+		return err
+	}
+
+	c.webhookMu.Lock()
+	defer c.webhookMu.Unlock()
+
+	for i, webhook := range c.Webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event) {
+			continue
+		}
+
+		delivery := webhookDelivery{
+			ID:            id + "-" + strconv.Itoa(i),
+			Webhook:       webhook.name(),
+			URL:           webhook.URL,
+			Event:         event,
+			Body:          body,
+			NextAttemptAt: tn.Format(time.DateTime),
+		}
+
+		j, err := json.Marshal(delivery)
+		if err != nil {
+			return err
+		}
+
+		err = dbc.SetMap(webhookOutboxKey, delivery.ID, string(j))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newWebhookDeliveryID() (string, error) {
+	b := make([]byte, 8)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// signWebhook 返回 X-Spotify-Insights-Signature 的值
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff 返回第 attempts 次失败后的等待时间, 从一分钟开始翻倍, 最多 webhookMaxBackoff
+func webhookBackoff(attempts int) time.Duration {
+	d := time.Minute << min(attempts-1, 16)
+	return min(d, webhookMaxBackoff)
+}
+
+// RunWebhookDispatcher 定时发送发件箱中到期的 Webhook 事件, 失败时按退避时间重试, interval 为 0 时使用 30 秒
+// 发件箱存储在数据库中, 程序重启后未发送的事件会继续发送, Webhook 的名称重复时不会发送
+func (c *Client) RunWebhookDispatcher(dbc dbClient, interval time.Duration) {
+	if interval == 0 {
+		interval = webhookDefaultPollInterval
+	}
+
+	err := checkWebhookNames(c.Webhooks)
+	if err != nil {
+		slog.Error("Webhook 配置错误, 不发送事件", "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	httpClient := &http.Client{Timeout: webhookRequestTimeout}
+
+	for ; ; <-ticker.C {
+		err := c.doJob(dbc, "webhooks", func(dbc dbClient) error {
+			return c.dispatchWebhooks(dbc, httpClient)
+		})
+		if err != nil {
+			slog.Warn("发送 Webhook 事件失败", "error", err)
+		}
+	}
+}
+
+// dispatchWebhooks 发送所有到期的事件, 发送成功或超过重试次数的会从发件箱中移除
+func (c *Client) dispatchWebhooks(dbc dbClient, httpClient *http.Client) error {
+	c.webhookMu.Lock()
+	all, err := dbc.GetMapAll(webhookOutboxKey)
+	c.webhookMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tn := time.Now()
+	done := map[string]bool{}
+	updated := map[string]string{}
+
+	for id, s := range all {
+		// 已完成
+		if s == "" {
+			continue
+		}
+
+		var delivery webhookDelivery
+
+		err = json.Unmarshal([]byte(s), &delivery)
+		if err != nil {
+			return err
+		}
+
+		nextAttemptAt, err := time.ParseInLocation(time.DateTime, delivery.NextAttemptAt, time.Local)
+		if err != nil {
+			return err
+		}
+
+		if tn.Before(nextAttemptAt) {
+			continue
+		}
+
+		if delivery.Webhook == "" {
+			delivery.Webhook = delivery.URL
+		}
+
+		i := slices.IndexFunc(c.Webhooks, func(webhook Webhook) bool {
+			return webhook.name() == delivery.Webhook
+		})
+		if i == -1 {
+			slog.Warn("Webhook 已不在配置中, 已放弃事件", "事件", delivery.Event, "Webhook", delivery.Webhook, "ID", id)
+			done[id] = true
+			continue
+		}
+
+		delivery.URL = c.Webhooks[i].URL
+
+		err = sendWebhook(httpClient, delivery, c.Webhooks[i].Secret)
+		if err == nil {
+			slog.Debug("Webhook 事件发送成功", "事件", delivery.Event, "URL", delivery.URL, "ID", id)
+			done[id] = true
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+
+		if delivery.Attempts >= webhookMaxAttempts {
+			slog.Error("Webhook 事件超过重试次数, 已放弃", "事件", delivery.Event, "URL", delivery.URL, "ID", id, "error", err)
+			done[id] = true
+			continue
+		}
+
+		backoff := webhookBackoff(delivery.Attempts)
+		delivery.NextAttemptAt = tn.Add(backoff).Format(time.DateTime)
+
+		slog.Warn("Webhook 事件发送失败, 稍后重试", "事件", delivery.Event, "URL", delivery.URL, "等待", backoff, "error", err)
+
+		j, err := json.Marshal(delivery)
+		if err != nil {
+			return err
+		}
+
+		updated[id] = string(j)
+	}
+
+	if len(done) == 0 && len(updated) == 0 {
+		return nil
+	}
+
+	return c.updateWebhookOutbox(dbc, done, updated)
+}
+
+// updateWebhookOutbox 原地更新需要重试的事件, 并将完成的事件标记为空字符串
+// 数据库没有删除字典字段的操作, 只有全部事件都已完成时才删除整个发件箱, 失败时不会丢失未发送的事件
+func (c *Client) updateWebhookOutbox(dbc dbClient, done map[string]bool, updated map[string]string) error {
+	for id, s := range updated {
+		err := dbc.SetMap(webhookOutboxKey, id, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range done {
+		err := dbc.SetMap(webhookOutboxKey, id, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	c.webhookMu.Lock()
+	defer c.webhookMu.Unlock()
+
+	all, err := dbc.GetMapAll(webhookOutboxKey)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range all {
+		if s != "" {
+			return nil
+		}
+	}
+
+	return dbc.Delete(webhookOutboxKey)
+}
+
+func sendWebhook(httpClient *http.Client, delivery webhookDelivery, secret string) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "spotify-insights-go")
+	req.Header.Set("X-Spotify-Insights-Event", delivery.Event)
+	req.Header.Set("X-Spotify-Insights-Delivery", delivery.ID)
+	req.Header.Set("X-Spotify-Insights-Timestamp", timestamp)
+
+	if secret != "" {
+		req.Header.Set("X-Spotify-Insights-Signature", signWebhook(secret, timestamp, delivery.Body))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("接收方返回 " + resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,461 @@
+package spotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDB 是测试用的内存数据库
+type memDB struct {
+	mu sync.Mutex
+	s  map[string]string
+	m  map[string]map[string]string
+	l  map[string][]string
+}
+
+func newMemDB() *memDB {
+	return &memDB{s: map[string]string{}, m: map[string]map[string]string{}, l: map[string][]string{}}
+}
+
+func (d *memDB) SetString(key string, value string, ex *time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.s[key] = value
+	return nil
+}
+
+func (d *memDB) GetString(key string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.s[key], nil
+}
+
+func (d *memDB) SetMap(key, field, value string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.m[key] == nil {
+		d.m[key] = map[string]string{}
+	}
+	d.m[key][field] = value
+	return nil
+}
+
+func (d *memDB) GetMapStr(key, field string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.m[key][field], nil
+}
+
+func (d *memDB) GetMapInt64(key, field string) (int64, error) {
+	s, _ := d.GetMapStr(key, field)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func (d *memDB) GetMapLen(key string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return int64(len(d.m[key])), nil
+}
+
+func (d *memDB) GetMapAll(key string) (map[string]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	all := map[string]string{}
+	for field, value := range d.m[key] {
+		all[field] = value
+	}
+	return all, nil
+}
+
+func (d *memDB) CheckIfMapFieldExists(key, field string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.m[key][field]
+	return ok, nil
+}
+
+func (d *memDB) AppendSlice(key string, values []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.l[key] = append(d.l[key], values...)
+	return nil
+}
+
+// GetSlice 与 LRANGE 相同, 下标可以为负数
+func (d *memDB) GetSlice(key string, start, stop int64) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l := d.l[key]
+	n := int64(len(l))
+	if start < 0 {
+		start = max(start+n, 0)
+	}
+	if stop < 0 {
+		stop += n
+	}
+	stop = min(stop, n-1)
+	if start > stop {
+		return nil, nil
+	}
+	return append([]string{}, l[start:stop+1]...), nil
+}
+
+func (d *memDB) GetSliceByIndex(key string, index int64) (string, error) {
+	values, err := d.GetSlice(key, index, index)
+	if err != nil || len(values) == 0 || index < -int64(len(d.l[key])) {
+		return "", err
+	}
+	return values[0], nil
+}
+
+func (d *memDB) GetSliceLen(key string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return int64(len(d.l[key])), nil
+}
+
+func (d *memDB) Delete(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.s, key)
+	delete(d.m, key)
+	delete(d.l, key)
+	return nil
+}
+
+// webhookRequest 是接收方收到的一次请求
+type webhookRequest struct {
+	path      string
+	event     string
+	delivery  string
+	timestamp string
+	signature string
+	body      []byte
+}
+
+// newWebhookReceiver 返回记录所有请求的接收方, failures 为每个路径返回 500 的次数
+func newWebhookReceiver(t *testing.T, failures map[string]int) (*httptest.Server, func() []webhookRequest) {
+	var mu sync.Mutex
+	var requests []webhookRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, webhookRequest{
+			path:      r.URL.Path,
+			event:     r.Header.Get("X-Spotify-Insights-Event"),
+			delivery:  r.Header.Get("X-Spotify-Insights-Delivery"),
+			timestamp: r.Header.Get("X-Spotify-Insights-Timestamp"),
+			signature: r.Header.Get("X-Spotify-Insights-Signature"),
+			body:      body,
+		})
+
+		if failures[r.URL.Path] > 0 {
+			failures[r.URL.Path]--
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest{}, requests...)
+	}
+}
+
+func getWebhookDeliveries(t *testing.T, dbc dbClient) []webhookDelivery {
+	t.Helper()
+
+	all, err := dbc.GetMapAll(webhookOutboxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deliveries []webhookDelivery
+
+	for _, s := range all {
+		// 已完成
+		if s == "" {
+			continue
+		}
+
+		var delivery webhookDelivery
+
+		err = json.Unmarshal([]byte(s), &delivery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries
+}
+
+// TestWebhookSignature 同一 URL 的两个 Webhook 使用各自的 Secret 签名, 调换配置顺序后不受影响
+func TestWebhookSignature(t *testing.T) {
+	srv, requests := newWebhookReceiver(t, nil)
+	dbc := newMemDB()
+
+	c := &Client{Webhooks: []Webhook{
+		{Name: "plays", URL: srv.URL + "/hook", Secret: "secret-a", Events: []string{WebhookNewPlays}},
+		{Name: "all", URL: srv.URL + "/hook", Secret: "secret-b"},
+	}}
+
+	err := c.enqueueWebhook(dbc, WebhookNewPlays, map[string]any{"plays": []PlayedTrack{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.enqueueWebhook(dbc, WebhookGapDetected, PlaybackGap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Webhooks[0], c.Webhooks[1] = c.Webhooks[1], c.Webhooks[0]
+
+	err = c.dispatchWebhooks(dbc, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("收到 %d 个请求, 应为 3 个", len(got))
+	}
+
+	signed := map[string]int{}
+
+	for _, r := range got {
+		var payload WebhookPayload
+
+		err = json.Unmarshal(r.body, &payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if payload.Event != r.event {
+			t.Errorf("请求体中的事件 %q 与请求头 %q 不一致", payload.Event, r.event)
+		}
+
+		switch r.signature {
+		case signWebhook("secret-a", r.timestamp, r.body):
+			signed["secret-a"]++
+
+			if r.event != WebhookNewPlays {
+				t.Errorf("plays 收到了未订阅的事件 %q", r.event)
+			}
+		case signWebhook("secret-b", r.timestamp, r.body):
+			signed["secret-b"]++
+		default:
+			t.Errorf("事件 %q 的签名错误: %s", r.event, r.signature)
+		}
+	}
+
+	if signed["secret-a"] != 1 || signed["secret-b"] != 2 {
+		t.Errorf("签名数量错误: %v", signed)
+	}
+
+	if deliveries := getWebhookDeliveries(t, dbc); len(deliveries) != 0 {
+		t.Errorf("发送成功后发件箱中仍有 %d 个事件", len(deliveries))
+	}
+}
+
+// TestWebhookRetry 失败后按退避时间重试, 超过重试次数后放弃
+func TestWebhookRetry(t *testing.T) {
+	srv, requests := newWebhookReceiver(t, map[string]int{"/flaky": 2, "/down": webhookMaxAttempts})
+	dbc := newMemDB()
+
+	c := &Client{Webhooks: []Webhook{
+		{URL: srv.URL + "/flaky"},
+		{URL: srv.URL + "/down"},
+	}}
+
+	err := c.enqueueWebhook(dbc, WebhookNewPlays, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempts := 1; attempts <= webhookMaxAttempts; attempts++ {
+		before := time.Now()
+
+		err = c.dispatchWebhooks(dbc, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 退避时间未到时不会发送
+		n := len(requests())
+
+		err = c.dispatchWebhooks(dbc, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(requests()) != n {
+			t.Fatalf("第 %d 次失败后未等待退避时间就重试了", attempts)
+		}
+
+		for _, delivery := range getWebhookDeliveries(t, dbc) {
+			if delivery.Attempts != attempts {
+				t.Fatalf("%s 的重试次数为 %d, 应为 %d", delivery.URL, delivery.Attempts, attempts)
+			}
+
+			if delivery.LastError == "" {
+				t.Errorf("%s 没有记录错误", delivery.URL)
+			}
+
+			nextAttemptAt, err := time.ParseInLocation(time.DateTime, delivery.NextAttemptAt, time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// NextAttemptAt 精确到秒
+			want := before.Add(webhookBackoff(attempts)).Truncate(time.Second)
+			if nextAttemptAt.Before(want) || nextAttemptAt.After(want.Add(time.Second*2)) {
+				t.Errorf("第 %d 次失败后下次发送时间为 %s, 应为 %s 左右", attempts, nextAttemptAt, want)
+			}
+
+			// 模拟退避时间已过
+			delivery.NextAttemptAt = time.Now().Add(-time.Second).Format(time.DateTime)
+
+			j, err := json.Marshal(delivery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = dbc.SetMap(webhookOutboxKey, delivery.ID, string(j))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	count := map[string]int{}
+	for _, r := range requests() {
+		count[r.path]++
+	}
+
+	if count["/flaky"] != 3 {
+		t.Errorf("/flaky 收到 %d 个请求, 应为失败两次后成功共 3 个", count["/flaky"])
+	}
+
+	if count["/down"] != webhookMaxAttempts {
+		t.Errorf("/down 收到 %d 个请求, 应为 %d 个", count["/down"], webhookMaxAttempts)
+	}
+
+	if deliveries := getWebhookDeliveries(t, dbc); len(deliveries) != 0 {
+		t.Errorf("超过重试次数后发件箱中仍有 %d 个事件", len(deliveries))
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, time.Minute * 2},
+		{3, time.Minute * 4},
+		{9, time.Hour*4 + time.Minute*16},
+		{10, webhookMaxBackoff},
+		{100, webhookMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %s, 应为 %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// TestWebhookOutboxRestart 发件箱存储在数据库中, 重启后由新的 Client 继续发送, 已移除的 Webhook 的事件会被放弃
+func TestWebhookOutboxRestart(t *testing.T) {
+	srv, requests := newWebhookReceiver(t, nil)
+	dbc := newMemDB()
+
+	webhooks := []Webhook{
+		{Name: "kept", URL: srv.URL + "/old", Secret: "old-secret"},
+		{Name: "removed", URL: srv.URL + "/removed"},
+	}
+
+	before := &Client{Webhooks: webhooks}
+
+	err := before.enqueueWebhook(dbc, WebhookRepairPerformed, map[string]any{"kind": RepairAggregates})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧版本的事件没有名称
+	err = dbc.SetMap(webhookOutboxKey, "legacy", `{"id":"legacy","url":"`+srv.URL+`/legacy","event":"new-plays","body":{},"attempts":0,"next_attempt_at":"2000-01-01 00:00:00"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries := getWebhookDeliveries(t, dbc); len(deliveries) != 3 {
+		t.Fatalf("发件箱中有 %d 个事件, 应为 3 个", len(deliveries))
+	}
+
+	// 重启后修改了 kept 的地址与 Secret, 移除了 removed
+	after := &Client{Webhooks: []Webhook{
+		{URL: srv.URL + "/legacy"},
+		{Name: "kept", URL: srv.URL + "/new", Secret: "new-secret"},
+	}}
+
+	err = after.dispatchWebhooks(dbc, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("收到 %d 个请求, 应为 2 个", len(got))
+	}
+
+	for _, r := range got {
+		switch r.path {
+		case "/new":
+			if r.signature != signWebhook("new-secret", r.timestamp, r.body) {
+				t.Errorf("重启后的签名错误: %s", r.signature)
+			}
+		case "/legacy":
+			if r.delivery != "legacy" || r.signature != "" {
+				t.Errorf("旧版本的事件发送错误: %+v", r)
+			}
+		default:
+			t.Errorf("不应发送到 %s", r.path)
+		}
+	}
+
+	if deliveries := getWebhookDeliveries(t, dbc); len(deliveries) != 0 {
+		t.Errorf("发件箱中仍有 %d 个事件", len(deliveries))
+	}
+}
+
+func TestCheckWebhookNames(t *testing.T) {
+	err := checkWebhookNames([]Webhook{{URL: "https://example.com/a"}, {URL: "https://example.com/a"}})
+	if err == nil {
+		t.Error("同一 URL 未设置名称时应返回错误")
+	}
+
+	err = checkWebhookNames([]Webhook{{Name: "a", URL: "https://example.com/a"}, {Name: "b", URL: "https://example.com/a"}})
+	if err != nil {
+		t.Error(err)
+	}
+}